@@ -0,0 +1,73 @@
+package zenoh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// EncodeAttachment encodes key/value pairs into attachment bytes.
+//
+// The layout matches zenoh-ext serialization of a map of strings
+// (a LEB128 count followed by length-prefixed keys and values), so
+// attachments can be decoded by Zenoh applications in other languages.
+// Keys are written in sorted order.
+func EncodeAttachment(kv map[string]string) []byte {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := binary.AppendUvarint(nil, uint64(len(kv)))
+	for _, k := range keys {
+		buf = appendString(buf, k)
+		buf = appendString(buf, kv[k])
+	}
+	return buf
+}
+
+// DecodeAttachment decodes attachment bytes written by EncodeAttachment.
+func DecodeAttachment(data []byte) (map[string]string, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("zenoh: invalid attachment: bad entry count")
+	}
+	data = data[n:]
+
+	// Every entry takes at least two bytes, which bounds the allocation
+	if count > uint64(len(data)/2) {
+		return nil, fmt.Errorf("zenoh: invalid attachment: %d entries in %d bytes", count, len(data))
+	}
+
+	kv := make(map[string]string, count)
+	for i := uint64(0); i < count; i++ {
+		var k, v string
+		var err error
+		if k, data, err = readString(data); err != nil {
+			return nil, err
+		}
+		if v, data, err = readString(data); err != nil {
+			return nil, err
+		}
+		kv[k] = v
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("zenoh: invalid attachment: %d trailing bytes", len(data))
+	}
+	return kv, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func readString(data []byte) (string, []byte, error) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n) {
+		return "", nil, fmt.Errorf("zenoh: invalid attachment: truncated string")
+	}
+	end := n + int(size)
+	return string(data[n:end]), data[end:], nil
+}
@@ -0,0 +1,146 @@
+package zenoh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AuthConfig configures user/password authentication between Zenoh
+// nodes ("usrpwd" in the Zenoh configuration).
+//
+// User and Password are the credentials this node presents. A router
+// that enforces authentication lists the accepted credentials in
+// DictionaryFile, one "user:password" per line.
+//
+// zenoh-c 1.0 fails z_open with the same code for rejected credentials
+// as for an unreachable router, so Open reports both as
+// ErrConnectionFailed. Zenoh has no pre-shared-key authentication; use
+// a shared dictionary of credentials, or mutual TLS (see TLSConfig).
+type AuthConfig struct {
+	// User and Password are presented when opening a session.
+	// Both must be set, or both left empty.
+	User     string
+	Password string
+
+	// DictionaryFile is the path of the credentials accepted from
+	// remote nodes (optional).
+	DictionaryFile string
+}
+
+// authConfigPath is the configuration section of user/password authentication.
+const authConfigPath = "transport/auth/usrpwd"
+
+// redacted replaces secret values in String output.
+const redacted = "***"
+
+// WithAuth returns a copy of the config with the specified credentials.
+func (c Config) WithAuth(auth AuthConfig) Config {
+	c.Auth = &auth
+	return c
+}
+
+// entries returns the configuration values for the set fields.
+func (a *AuthConfig) entries() []configEntry {
+	if a == nil {
+		return nil
+	}
+
+	var entries []configEntry
+	for _, f := range []struct{ key, value string }{
+		{"user", a.User},
+		{"password", a.Password},
+		{"dictionary_file", a.DictionaryFile},
+	} {
+		if f.value != "" {
+			entries = append(entries, configEntry{path: authConfigPath + "/" + f.key, value: f.value})
+		}
+	}
+	return entries
+}
+
+// validate checks that credentials are complete and the dictionary exists.
+func (a *AuthConfig) validate() error {
+	if a == nil {
+		return nil
+	}
+	if (a.User == "") != (a.Password == "") {
+		return errors.New("auth requires both user and password")
+	}
+	if a.DictionaryFile != "" {
+		if _, err := os.Stat(a.DictionaryFile); err != nil {
+			return fmt.Errorf("auth dictionary_file: %w", err)
+		}
+	}
+	return nil
+}
+
+// String returns the configuration as JSON5 with passwords and inline
+// private keys replaced by "***", so configs can be logged safely.
+func (c Config) String() string {
+	doc, err := c.document()
+	if err != nil {
+		return fmt.Sprintf("invalid config: %v", err)
+	}
+
+	data, err := json.Marshal(redactConfig(doc))
+	if err != nil {
+		return fmt.Sprintf("invalid config: %v", err)
+	}
+	return string(data)
+}
+
+// GoString redacts secrets for the %#v verb, like String.
+func (c Config) GoString() string {
+	return "zenoh.Config" + c.String()
+}
+
+// redactConfig returns a copy of a configuration document with the
+// values of secret keys replaced.
+func redactConfig(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			if secretConfigKey(k) {
+				m[k] = redacted
+			} else {
+				m[k] = redactConfig(item)
+			}
+		}
+		return m
+	case []any:
+		arr := make([]any, len(v))
+		for i, item := range v {
+			arr[i] = redactConfig(item)
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// secretConfigKey reports whether a configuration key holds a secret
+// rather than a reference to one: passwords and base64 private keys.
+func secretConfigKey(key string) bool {
+	return key == "password" || strings.HasSuffix(key, "private_key_base64")
+}
+
+// secretConfigPath reports whether the last key of a path is secret.
+func secretConfigPath(path string) bool {
+	return secretConfigKey(path[strings.LastIndex(path, "/")+1:])
+}
+
+// displayValue is the entry value for error messages, with secrets redacted.
+func (e configEntry) displayValue() string {
+	if secretConfigPath(e.path) {
+		return redacted
+	}
+	data, err := json.Marshal(redactConfig(e.value))
+	if err != nil {
+		return fmt.Sprint(e.value)
+	}
+	return string(data)
+}
@@ -0,0 +1,192 @@
+package zenoh
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// ChannelHandler selects how a channel subscriber queues samples
+// until they are received. Use FifoChannel or RingChannel.
+type ChannelHandler struct {
+	capacity int
+	ring     bool
+}
+
+// FifoChannel queues up to capacity samples in arrival order.
+// When the queue is full, delivery blocks until a sample is received,
+// applying backpressure to the sender; no sample is lost.
+func FifoChannel(capacity int) ChannelHandler {
+	return ChannelHandler{capacity: capacity}
+}
+
+// RingChannel keeps only the latest capacity samples. When the queue
+// is full, the oldest sample is dropped; delivery never blocks.
+func RingChannel(capacity int) ChannelHandler {
+	return ChannelHandler{capacity: capacity, ring: true}
+}
+
+// ChannelSubscriber is a subscriber whose samples are queued until
+// received with Recv or TryRecv. Close discards the queued samples.
+//
+// Example:
+//
+//	sub, err := session.SubscribeChan("reachy_mini/joints/*", zenoh.RingChannel(16))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer sub.Close()
+//
+//	for {
+//	    s, err := sub.Recv(ctx)
+//	    if err != nil {
+//	        break
+//	    }
+//	    log.Printf("Received: %s", s.Payload)
+//	}
+type ChannelSubscriber interface {
+	Subscriber
+
+	// Recv waits for the next sample. It returns ErrSubscriberClosed
+	// once the subscriber or its session is closed, or the ctx error.
+	Recv(ctx context.Context) (Sample, error)
+
+	// TryRecv returns the next sample if one is queued.
+	TryRecv() (Sample, bool)
+
+	// Samples returns an iterator over received samples, which ends
+	// when ctx is done or the subscriber is closed:
+	//
+	//	for s := range sub.Samples(ctx) {
+	//	    log.Printf("Received: %s", s.Payload)
+	//	}
+	Samples(ctx context.Context) iter.Seq[Sample]
+}
+
+// channelSubscriber implements ChannelSubscriber on top of a backend
+// subscriber that pushes to queue and closes it on Close.
+type channelSubscriber struct {
+	Subscriber
+	queue *sampleQueue
+}
+
+func (s *channelSubscriber) Recv(ctx context.Context) (Sample, error) {
+	return s.queue.recv(ctx)
+}
+
+func (s *channelSubscriber) TryRecv() (Sample, bool) {
+	return s.queue.tryRecv()
+}
+
+func (s *channelSubscriber) Samples(ctx context.Context) iter.Seq[Sample] {
+	return func(yield func(Sample) bool) {
+		for {
+			sample, err := s.queue.recv(ctx)
+			if err != nil || !yield(sample) {
+				return
+			}
+		}
+	}
+}
+
+// sampleQueue is the bounded queue behind a channel subscriber.
+type sampleQueue struct {
+	ring    bool
+	samples chan Sample
+
+	// mu serializes ring pushes, which drop and send as one step.
+	mu sync.Mutex
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSampleQueue creates the queue selected by handler.
+func newSampleQueue(handler ChannelHandler) (*sampleQueue, error) {
+	if handler.capacity <= 0 {
+		return nil, fmt.Errorf("%w: channel capacity must be positive, got %d", ErrSubscribeFailed, handler.capacity)
+	}
+	return &sampleQueue{
+		ring:    handler.ring,
+		samples: make(chan Sample, handler.capacity),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// push queues a sample. It is the subscriber handler, so it runs on the
+// delivering thread: a full FIFO blocks it until a sample is received or
+// the queue is closed. Samples pushed after close are discarded.
+func (q *sampleQueue) push(sample Sample) {
+	select {
+	case <-q.done:
+		return
+	default:
+	}
+
+	if !q.ring {
+		select {
+		case q.samples <- sample:
+		case <-q.done:
+		}
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		select {
+		case q.samples <- sample:
+			return
+		default:
+		}
+		// Full: drop the oldest, unless a receiver just took it
+		select {
+		case <-q.samples:
+		default:
+		}
+	}
+}
+
+func (q *sampleQueue) recv(ctx context.Context) (Sample, error) {
+	select {
+	case <-q.done:
+		return Sample{}, ErrSubscriberClosed
+	default:
+	}
+
+	select {
+	case sample := <-q.samples:
+		return sample, nil
+	case <-q.done:
+		return Sample{}, ErrSubscriberClosed
+	case <-ctx.Done():
+		return Sample{}, contextError(ctx.Err())
+	}
+}
+
+func (q *sampleQueue) tryRecv() (Sample, bool) {
+	select {
+	case <-q.done:
+		return Sample{}, false
+	default:
+	}
+
+	select {
+	case sample := <-q.samples:
+		return sample, true
+	default:
+		return Sample{}, false
+	}
+}
+
+// close wakes blocked pushes and receivers. Safe to call on a nil queue
+// and more than once.
+func (q *sampleQueue) close() {
+	if q == nil {
+		return
+	}
+	q.closeOnce.Do(func() {
+		close(q.done)
+	})
+}
@@ -1,14 +1,30 @@
 package zenoh
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"time"
 )
 
+// EnvConfig is the environment variable holding the path of a Zenoh
+// configuration file, read by ConfigFromEnv.
+const EnvConfig = "ZENOH_CONFIG"
+
+// defaultConnectTimeout is the ConnectTimeout of the config constructors.
+const defaultConnectTimeout = 5 * time.Second
+
 // Config holds Zenoh session configuration.
+//
+// The typed fields cover the common settings. A complete Zenoh
+// configuration can be loaded with ConfigFromFile or ConfigFromJSON5;
+// the typed fields are then initialized from the document and only
+// the ones changed afterwards override it. Insert sets any other value.
 type Config struct {
-	// Mode is either "peer" or "client".
+	// Mode is "peer", "client" or "router".
 	// Client mode requires at least one endpoint.
 	// Peer mode can use multicast discovery.
 	Mode string
@@ -24,13 +40,43 @@ type Config struct {
 	// ConnectTimeout for connection attempts.
 	// Default: 5 seconds
 	ConnectTimeout time.Duration
+
+	// DisableMulticastScouting turns off multicast discovery of peers
+	// and routers, e.g. on networks where multicast is filtered.
+	DisableMulticastScouting bool
+
+	// TLS configures tls/ and quic/ links (optional).
+	TLS *TLSConfig
+
+	// Auth holds user/password credentials (optional).
+	// Secrets are redacted by String.
+	Auth *AuthConfig
+
+	// source is the document loaded by ConfigFromFile or ConfigFromJSON5.
+	source *configSource
+
+	// inserts are the raw values set with Insert, applied last.
+	inserts []configEntry
+}
+
+// configSource is a complete Zenoh configuration document that the
+// typed fields and inserts are layered on.
+type configSource struct {
+	// text is the original JSON5, handed to zenoh-c unchanged.
+	text string
+
+	// doc is the parsed document.
+	doc map[string]any
+
+	// typed holds the typed fields as found in the document.
+	typed Config
 }
 
 // DefaultConfig returns a config for local peer mode.
 func DefaultConfig() Config {
 	return Config{
 		Mode:           ModePeer,
-		ConnectTimeout: 5 * time.Second,
+		ConnectTimeout: defaultConnectTimeout,
 	}
 }
 
@@ -39,7 +85,7 @@ func ClientConfig(endpoints ...string) Config {
 	return Config{
 		Mode:           ModeClient,
 		Endpoints:      endpoints,
-		ConnectTimeout: 5 * time.Second,
+		ConnectTimeout: defaultConnectTimeout,
 	}
 }
 
@@ -48,22 +94,154 @@ func PeerConfig(listenEndpoints ...string) Config {
 	return Config{
 		Mode:            ModePeer,
 		ListenEndpoints: listenEndpoints,
-		ConnectTimeout:  5 * time.Second,
+		ConnectTimeout:  defaultConnectTimeout,
 	}
 }
 
+// ConfigFromFile loads a standard Zenoh JSON5 configuration file.
+func ConfigFromFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+
+	c, err := ConfigFromJSON5(string(data))
+	if err != nil {
+		return Config{}, fmt.Errorf("load config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ConfigFromEnv loads the Zenoh configuration file named by the
+// ZENOH_CONFIG environment variable.
+func ConfigFromEnv() (Config, error) {
+	path := os.Getenv(EnvConfig)
+	if path == "" {
+		return Config{}, fmt.Errorf("load config: %s is not set", EnvConfig)
+	}
+	return ConfigFromFile(path)
+}
+
+// ConfigFromJSON5 parses a complete Zenoh JSON5 configuration.
+// Settings the document leaves out keep their zenoh-c defaults.
+func ConfigFromJSON5(text string) (Config, error) {
+	v, err := parseJSON5(text)
+	if err != nil {
+		return Config{}, err
+	}
+	doc, ok := v.(map[string]any)
+	if !ok {
+		return Config{}, errors.New("config must be a JSON5 object")
+	}
+
+	typed := typedConfig(doc)
+	c := typed
+	c.Endpoints = slices.Clone(typed.Endpoints)
+	c.ListenEndpoints = slices.Clone(typed.ListenEndpoints)
+	c.source = &configSource{text: text, doc: doc, typed: typed}
+	return c, nil
+}
+
+// typedConfig extracts the typed fields from a configuration document.
+// Values in a form the typed fields cannot express are left unset.
+func typedConfig(doc map[string]any) Config {
+	c := Config{
+		Mode:           ModePeer,
+		ConnectTimeout: defaultConnectTimeout,
+	}
+	if mode, ok := doc["mode"].(string); ok {
+		c.Mode = mode
+	}
+	if connect, ok := doc["connect"].(map[string]any); ok {
+		c.Endpoints = stringList(connect["endpoints"])
+		if ms, ok := connect["timeout_ms"].(json.Number); ok {
+			if n, err := ms.Int64(); err == nil && n > 0 {
+				c.ConnectTimeout = time.Duration(n) * time.Millisecond
+			}
+		}
+	}
+	if listen, ok := doc["listen"].(map[string]any); ok {
+		c.ListenEndpoints = stringList(listen["endpoints"])
+	}
+	if scouting, ok := doc["scouting"].(map[string]any); ok {
+		if multicast, ok := scouting["multicast"].(map[string]any); ok {
+			c.DisableMulticastScouting = multicast["enabled"] == false
+		}
+	}
+	return c
+}
+
+// stringList converts a JSON array of strings, or returns nil.
+func stringList(v any) []string {
+	if list, ok := v.([]string); ok {
+		return list
+	}
+	arr, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	list := make([]string, 0, len(arr))
+	for _, item := range arr {
+		s, ok := item.(string)
+		if !ok {
+			return nil
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// Insert sets a raw JSON5 value at a slash-separated configuration path,
+// for settings that have no typed field:
+//
+//	cfg.Insert("timestamping/enabled", "true")
+//
+// Inserted values are applied after the typed fields, with
+// zc_config_insert_json5 in the CGO backend.
+func (c *Config) Insert(path string, json5Value string) error {
+	if path == "" || strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("invalid config path %q", path)
+	}
+	v, err := parseJSON5(json5Value)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	// Clip so copies of the config never share appended entries
+	c.inserts = append(slices.Clip(c.inserts), configEntry{path: path, value: v, raw: json5Value})
+	return nil
+}
+
 // Validate checks the configuration for errors.
 func (c Config) Validate() error {
-	if c.Mode == ModeClient && len(c.Endpoints) == 0 {
+	// A loaded document or an insert may provide endpoints in other forms
+	if c.Mode == ModeClient && len(c.Endpoints) == 0 && c.source == nil && !c.inserted("connect") {
 		return errors.New("client mode requires at least one endpoint")
 	}
-	if c.Mode != ModePeer && c.Mode != ModeClient {
-		return fmt.Errorf("invalid mode: %s (must be %q or %q)", c.Mode, ModePeer, ModeClient)
+	if c.Mode != ModePeer && c.Mode != ModeClient && c.Mode != ModeRouter {
+		return fmt.Errorf("invalid mode: %s (must be %q, %q or %q)", c.Mode, ModePeer, ModeClient, ModeRouter)
 	}
 	if c.ConnectTimeout <= 0 {
 		return errors.New("connect timeout must be positive")
 	}
-	return nil
+	if err := c.Auth.validate(); err != nil {
+		return err
+	}
+	doc, err := c.document()
+	if err != nil {
+		return err
+	}
+	return validateTLS(doc)
+}
+
+// inserted reports whether Insert set path or a value below it.
+func (c Config) inserted(path string) bool {
+	for _, e := range c.inserts {
+		if e.path == path || strings.HasPrefix(e.path, path+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 // WithTimeout returns a copy of the config with the specified timeout.
@@ -72,6 +250,142 @@ func (c Config) WithTimeout(timeout time.Duration) Config {
 	return c
 }
 
+// JSON5 returns the configuration as a Zenoh JSON5 document.
+//
+// This is the loaded document, if any, with the typed fields and inserts
+// applied; the values the CGO backend applies on top of the zenoh-c
+// defaults. Strings are escaped with encoding/json, whose output is
+// valid JSON5. Unlike String, secrets are included.
+func (c Config) JSON5() (string, error) {
+	doc, err := c.document()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("encode config: %w", err)
+	}
+	return string(data), nil
+}
+
+// document returns the configuration as a parsed JSON document.
+func (c Config) document() (map[string]any, error) {
+	root := make(map[string]any)
+	if c.source != nil {
+		root = deepCopyJSON(c.source.doc).(map[string]any)
+	}
+	for _, e := range c.entries() {
+		if err := setConfigPath(root, e.path, e.value); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// configEntry is a single value of the Zenoh configuration,
+// addressed by a slash-separated path such as "connect/endpoints".
+type configEntry struct {
+	path  string
+	value any
+
+	// raw is the original JSON5 text of inserted values.
+	raw string
+}
+
+// entries returns the Zenoh configuration values to apply on top of the
+// loaded document (or the defaults), in order: typed fields that differ
+// from the document, TLS and auth settings, then inserts.
+func (c Config) entries() []configEntry {
+	var loaded Config
+	if c.source != nil {
+		loaded = c.source.typed
+	}
+
+	var entries []configEntry
+	if c.Mode != loaded.Mode {
+		entries = append(entries, configEntry{path: "mode", value: c.Mode})
+	}
+	if !slices.Equal(c.Endpoints, loaded.Endpoints) {
+		entries = append(entries, configEntry{path: "connect/endpoints", value: nonNil(c.Endpoints)})
+	}
+	if c.ConnectTimeout != loaded.ConnectTimeout && c.ConnectTimeout > 0 {
+		entries = append(entries, configEntry{path: "connect/timeout_ms", value: c.ConnectTimeout.Milliseconds()})
+	}
+	if !slices.Equal(c.ListenEndpoints, loaded.ListenEndpoints) {
+		entries = append(entries, configEntry{path: "listen/endpoints", value: nonNil(c.ListenEndpoints)})
+	}
+	if c.DisableMulticastScouting != loaded.DisableMulticastScouting {
+		entries = append(entries, configEntry{path: "scouting/multicast/enabled", value: !c.DisableMulticastScouting})
+	}
+	entries = append(entries, c.TLS.entries()...)
+	entries = append(entries, c.Auth.entries()...)
+	return append(entries, c.inserts...)
+}
+
+// nonNil returns an empty list instead of nil, so it encodes as [].
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// jsonValue encodes an entry value for zc_config_insert_json5.
+func (e configEntry) jsonValue() (string, error) {
+	if e.raw != "" {
+		return e.raw, nil
+	}
+	data, err := json.Marshal(e.value)
+	if err != nil {
+		return "", fmt.Errorf("encode config %s: %w", e.path, err)
+	}
+	return string(data), nil
+}
+
+// deepCopyJSON copies a parsed JSON document.
+func deepCopyJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, item := range v {
+			m[k] = deepCopyJSON(item)
+		}
+		return m
+	case []any:
+		arr := make([]any, len(v))
+		for i, item := range v {
+			arr[i] = deepCopyJSON(item)
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// setConfigPath stores value at path in a nested JSON object,
+// creating intermediate objects as needed.
+func setConfigPath(root map[string]any, path string, value any) error {
+	keys := strings.Split(path, "/")
+	node := root
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := node[key]
+		if !ok {
+			next := make(map[string]any)
+			node[key] = next
+			node = next
+			continue
+		}
+		next, ok := child.(map[string]any)
+		if !ok {
+			return fmt.Errorf("config path %s: %s is not an object", path, key)
+		}
+		node = next
+	}
+	node[keys[len(keys)-1]] = value
+	return nil
+}
+
 
 
 
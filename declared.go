@@ -0,0 +1,103 @@
+package zenoh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DeclaredKeyExpr is a key expression declared on a session.
+//
+// Declared key expressions are created via Session.DeclareKeyExpr().
+// The session then sends a short numeric ID instead of the full key on
+// the wire, which saves bandwidth for high-rate streams. Publishers,
+// subscribers and queries on the same key expression use the declared
+// one, whether created through DeclaredKeyExpr or the Session.
+//
+// Example:
+//
+//	ke, err := session.DeclareKeyExpr("reachy_mini/joint_positions")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer ke.Close()
+//
+//	pub, _ := ke.Publisher()
+type DeclaredKeyExpr interface {
+	// KeyExpr returns the declared key expression.
+	KeyExpr() KeyExpr
+
+	// Publisher declares a publisher on the key expression,
+	// like Session.Publisher.
+	Publisher(opts ...Option) (Publisher, error)
+
+	// Subscribe creates a subscriber on the key expression,
+	// like Session.Subscribe.
+	Subscribe(handler Handler) (Subscriber, error)
+
+	// Get queries the key expression, like Session.Get.
+	Get(ctx context.Context, opts ...Option) ([]Sample, error)
+
+	// Close undeclares the key expression once the publishers and
+	// subscribers using it, from any handle or the Session, are closed.
+	// After Close, the other methods return ErrInvalidKeyExpr.
+	Close() error
+}
+
+// declaredKeyExpr implements DeclaredKeyExpr for both backends, which
+// provide undeclare.
+type declaredKeyExpr struct {
+	session   Session
+	keyExpr   KeyExpr
+	undeclare func() error
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (d *declaredKeyExpr) KeyExpr() KeyExpr {
+	return d.keyExpr
+}
+
+func (d *declaredKeyExpr) Publisher(opts ...Option) (Publisher, error) {
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d.session.Publisher(d.keyExpr, opts...)
+}
+
+func (d *declaredKeyExpr) Subscribe(handler Handler) (Subscriber, error) {
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d.session.Subscribe(d.keyExpr, handler)
+}
+
+func (d *declaredKeyExpr) Get(ctx context.Context, opts ...Option) ([]Sample, error) {
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d.session.Get(ctx, d.keyExpr, opts...)
+}
+
+func (d *declaredKeyExpr) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	return d.undeclare()
+}
+
+// check returns an error once the key expression is undeclared.
+func (d *declaredKeyExpr) check() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return fmt.Errorf("%w: %s was undeclared", ErrInvalidKeyExpr, d.keyExpr)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package zenoh
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Encoding describes how a payload is encoded.
+//
+// An encoding is one of the well-known Zenoh encoding IDs, optionally
+// followed by a schema, e.g. "application/json;robot.JointState".
+// The zero value is EncodingZenohBytes, the Zenoh default.
+// Encodings are comparable with ==.
+type Encoding struct {
+	id     uint16
+	schema string
+}
+
+// Well-known encodings, in Zenoh ID order.
+var (
+	EncodingZenohBytes                    = Encoding{id: 0}
+	EncodingZenohString                   = Encoding{id: 1}
+	EncodingZenohSerialized               = Encoding{id: 2}
+	EncodingApplicationOctetStream        = Encoding{id: 3}
+	EncodingTextPlain                     = Encoding{id: 4}
+	EncodingApplicationJSON               = Encoding{id: 5}
+	EncodingTextJSON                      = Encoding{id: 6}
+	EncodingApplicationCDR                = Encoding{id: 7}
+	EncodingApplicationCBOR               = Encoding{id: 8}
+	EncodingApplicationYAML               = Encoding{id: 9}
+	EncodingTextYAML                      = Encoding{id: 10}
+	EncodingTextJSON5                     = Encoding{id: 11}
+	EncodingApplicationPythonSerialized   = Encoding{id: 12}
+	EncodingApplicationProtobuf           = Encoding{id: 13}
+	EncodingApplicationJavaSerialized     = Encoding{id: 14}
+	EncodingApplicationOpenMetricsText    = Encoding{id: 15}
+	EncodingImagePNG                      = Encoding{id: 16}
+	EncodingImageJPEG                     = Encoding{id: 17}
+	EncodingImageGIF                      = Encoding{id: 18}
+	EncodingImageBMP                      = Encoding{id: 19}
+	EncodingImageWebP                     = Encoding{id: 20}
+	EncodingApplicationXML                = Encoding{id: 21}
+	EncodingApplicationXWWWFormURLEncoded = Encoding{id: 22}
+	EncodingTextHTML                      = Encoding{id: 23}
+	EncodingTextXML                       = Encoding{id: 24}
+	EncodingTextCSS                       = Encoding{id: 25}
+	EncodingTextJavaScript                = Encoding{id: 26}
+	EncodingTextMarkdown                  = Encoding{id: 27}
+	EncodingTextCSV                       = Encoding{id: 28}
+	EncodingApplicationSQL                = Encoding{id: 29}
+	EncodingApplicationCoAPPayload        = Encoding{id: 30}
+	EncodingApplicationJSONPatchJSON      = Encoding{id: 31}
+	EncodingApplicationJSONSeq            = Encoding{id: 32}
+	EncodingApplicationJSONPath           = Encoding{id: 33}
+	EncodingApplicationJWT                = Encoding{id: 34}
+	EncodingApplicationMP4                = Encoding{id: 35}
+	EncodingApplicationSOAPXML            = Encoding{id: 36}
+	EncodingApplicationYANG               = Encoding{id: 37}
+	EncodingAudioAAC                      = Encoding{id: 38}
+	EncodingAudioFLAC                     = Encoding{id: 39}
+	EncodingAudioMP4                      = Encoding{id: 40}
+	EncodingAudioOGG                      = Encoding{id: 41}
+	EncodingAudioVorbis                   = Encoding{id: 42}
+	EncodingVideoH261                     = Encoding{id: 43}
+	EncodingVideoH263                     = Encoding{id: 44}
+	EncodingVideoH264                     = Encoding{id: 45}
+	EncodingVideoH265                     = Encoding{id: 46}
+	EncodingVideoH266                     = Encoding{id: 47}
+	EncodingVideoMP4                      = Encoding{id: 48}
+	EncodingVideoOGG                      = Encoding{id: 49}
+	EncodingVideoRaw                      = Encoding{id: 50}
+	EncodingVideoVP8                      = Encoding{id: 51}
+	EncodingVideoVP9                      = Encoding{id: 52}
+)
+
+// encodingNames maps well-known encoding IDs to their string form.
+var encodingNames = [...]string{
+	"zenoh/bytes",
+	"zenoh/string",
+	"zenoh/serialized",
+	"application/octet-stream",
+	"text/plain",
+	"application/json",
+	"text/json",
+	"application/cdr",
+	"application/cbor",
+	"application/yaml",
+	"text/yaml",
+	"text/json5",
+	"application/python-serialized-object",
+	"application/protobuf",
+	"application/java-serialized-object",
+	"application/openmetrics-text",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/bmp",
+	"image/webp",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"text/html",
+	"text/xml",
+	"text/css",
+	"text/javascript",
+	"text/markdown",
+	"text/csv",
+	"application/sql",
+	"application/coap-payload",
+	"application/json-patch+json",
+	"application/json-seq",
+	"application/jsonpath",
+	"application/jwt",
+	"application/mp4",
+	"application/soap+xml",
+	"application/yang",
+	"audio/aac",
+	"audio/flac",
+	"audio/mp4",
+	"audio/ogg",
+	"audio/vorbis",
+	"video/h261",
+	"video/h263",
+	"video/h264",
+	"video/h265",
+	"video/h266",
+	"video/mp4",
+	"video/ogg",
+	"video/raw",
+	"video/vp8",
+	"video/vp9",
+}
+
+// encodingIDs is the reverse of encodingNames.
+var encodingIDs = func() map[string]uint16 {
+	ids := make(map[string]uint16, len(encodingNames))
+	for id, name := range encodingNames {
+		ids[name] = uint16(id)
+	}
+	return ids
+}()
+
+// encodingSchemaSep separates the encoding from its schema.
+const encodingSchemaSep = ";"
+
+// ParseEncoding parses the string form of an encoding.
+//
+// A well-known prefix such as "application/json;my-schema" yields its ID
+// and schema. Any other string is kept as the schema of zenoh/bytes,
+// matching Zenoh's behavior for custom encodings.
+func ParseEncoding(s string) Encoding {
+	if s == "" {
+		return Encoding{}
+	}
+	prefix, schema, _ := strings.Cut(s, encodingSchemaSep)
+	if id, ok := encodingIDs[prefix]; ok {
+		return Encoding{id: id, schema: schema}
+	}
+	return Encoding{schema: s}
+}
+
+// ID returns the numeric Zenoh encoding ID.
+func (e Encoding) ID() uint16 {
+	return e.id
+}
+
+// Schema returns the encoding schema, or an empty string if none.
+func (e Encoding) Schema() string {
+	return e.schema
+}
+
+// WithSchema returns a copy of the encoding with the given schema.
+func (e Encoding) WithSchema(schema string) Encoding {
+	e.schema = schema
+	return e
+}
+
+// String returns the Zenoh string form, "prefix" or "prefix;schema".
+func (e Encoding) String() string {
+	var prefix string
+	if int(e.id) < len(encodingNames) {
+		prefix = encodingNames[e.id]
+	} else {
+		prefix = strconv.Itoa(int(e.id))
+	}
+	if e.schema == "" {
+		return prefix
+	}
+	return prefix + encodingSchemaSep + e.schema
+}
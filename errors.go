@@ -1,6 +1,10 @@
 package zenoh
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 // Common errors returned by zenoh-go operations.
 var (
@@ -22,10 +26,40 @@ var (
 	// ErrSubscribeFailed is returned when creating a subscription fails.
 	ErrSubscribeFailed = errors.New("zenoh: subscribe failed")
 
+	// ErrSubscriberClosed is returned when receiving from a closed
+	// channel subscriber.
+	ErrSubscriberClosed = errors.New("zenoh: subscriber closed")
+
 	// ErrQueryFailed is returned when a query operation fails.
 	ErrQueryFailed = errors.New("zenoh: query failed")
 )
 
+// ReplyError is an error reply sent by a queryable in response to Get.
+// It wraps ErrQueryFailed, so errors.Is(err, ErrQueryFailed) holds.
+type ReplyError struct {
+	// Payload is the error payload sent by the queryable.
+	Payload []byte
+}
+
+// Error implements the error interface.
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("%v: reply error: %s", ErrQueryFailed, e.Payload)
+}
+
+// Unwrap returns ErrQueryFailed.
+func (e *ReplyError) Unwrap() error {
+	return ErrQueryFailed
+}
+
+// contextError maps a context error to a zenoh error.
+// Deadline expiry is reported as ErrTimeout; both remain matchable with errors.Is.
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
+
 
 
 
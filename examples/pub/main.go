@@ -32,7 +32,7 @@ func main() {
 	defer session.Close()
 
 	info := session.Info()
-	log.Printf("Session opened (CGO: %v)", info.UsingCGO)
+	log.Printf("Session %s opened (CGO: %v)", info.ID, info.UsingCGO)
 
 	pub, err := session.Publisher(zenoh.KeyExpr(*keyExpr))
 	if err != nil {
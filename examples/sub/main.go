@@ -29,7 +29,7 @@ func main() {
 	defer session.Close()
 
 	info := session.Info()
-	log.Printf("Session opened (CGO: %v)", info.UsingCGO)
+	log.Printf("Session %s opened (CGO: %v)", info.ID, info.UsingCGO)
 
 	log.Printf("Subscribing to '%s'...", *keyExpr)
 
@@ -0,0 +1,344 @@
+package zenoh
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// parseJSON5 parses a JSON5 document, as used by Zenoh configuration files.
+//
+// Objects become map[string]any, arrays []any and numbers json.Number, so
+// the result can be re-encoded with encoding/json. Infinity and NaN are
+// rejected since they have no JSON representation.
+func parseJSON5(text string) (any, error) {
+	p := &json5Parser{text: text}
+	p.skipSpace()
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return nil, p.errorf("unexpected %q after value", p.text[p.pos])
+	}
+	return v, nil
+}
+
+type json5Parser struct {
+	text string
+	pos  int
+}
+
+func (p *json5Parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.text[:p.pos], "\n")
+	return fmt.Errorf("json5: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments.
+func (p *json5Parser) skipSpace() {
+	for p.pos < len(p.text) {
+		switch {
+		case strings.HasPrefix(p.text[p.pos:], "//"):
+			end := strings.IndexByte(p.text[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.text)
+				return
+			}
+			p.pos += end + 1
+		case strings.HasPrefix(p.text[p.pos:], "/*"):
+			end := strings.Index(p.text[p.pos+2:], "*/")
+			if end < 0 {
+				p.pos = len(p.text)
+				return
+			}
+			p.pos += end + 4
+		case strings.IndexByte(" \t\r\n\v\f", p.text[p.pos]) >= 0:
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *json5Parser) value() (any, error) {
+	if p.pos >= len(p.text) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch c := p.text[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	default:
+		word := p.identifier()
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "Infinity", "NaN":
+			return nil, p.errorf("%s has no JSON representation", word)
+		case "":
+			return nil, p.errorf("unexpected %q", c)
+		default:
+			return nil, p.errorf("unexpected identifier %q", word)
+		}
+	}
+}
+
+func (p *json5Parser) object() (map[string]any, error) {
+	p.pos++ // '{'
+	obj := make(map[string]any)
+	for {
+		p.skipSpace()
+		if p.pos < len(p.text) && p.text[p.pos] == '}' {
+			p.pos++
+			return obj, nil
+		}
+
+		var key string
+		if p.pos < len(p.text) && (p.text[p.pos] == '"' || p.text[p.pos] == '\'') {
+			var err error
+			if key, err = p.string(); err != nil {
+				return nil, err
+			}
+		} else if key = p.identifier(); key == "" {
+			return nil, p.errorf("expected object key")
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.text) || p.text[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		p.pos++
+		p.skipSpace()
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+
+		if !p.separator('}') {
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+func (p *json5Parser) array() ([]any, error) {
+	p.pos++ // '['
+	arr := []any{}
+	for {
+		p.skipSpace()
+		if p.pos < len(p.text) && p.text[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		if !p.separator(']') {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// separator consumes a ',' or, without consuming it, a closing delimiter.
+func (p *json5Parser) separator(closing byte) bool {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return false
+	}
+	switch p.text[p.pos] {
+	case ',':
+		p.pos++
+		return true
+	case closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *json5Parser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		isLetter := c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isLetter && (p.pos == start || c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.text[start:p.pos]
+}
+
+func (p *json5Parser) string() (string, error) {
+	quote := p.text[p.pos]
+	p.pos++
+
+	var sb strings.Builder
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c == '\n':
+			return "", p.errorf("unterminated string")
+		case c != '\\':
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		// Escape sequence
+		p.pos++
+		if p.pos >= len(p.text) {
+			break
+		}
+		if sep := p.text[p.pos:]; strings.HasPrefix(sep, "\u2028") || strings.HasPrefix(sep, "\u2029") {
+			// Line continuation with a Unicode line or paragraph separator
+			p.pos += len("\u2028")
+			continue
+		}
+		e := p.text[p.pos]
+		p.pos++
+		switch e {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		case '0':
+			if p.pos < len(p.text) && p.text[p.pos] >= '0' && p.text[p.pos] <= '9' {
+				return "", p.errorf("invalid \\0 escape followed by a digit")
+			}
+			sb.WriteByte(0)
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			return "", p.errorf("invalid \\%c escape", e)
+		case '\n':
+			// Line continuation
+		case '\r':
+			// Line continuation, also for CRLF
+			if p.pos < len(p.text) && p.text[p.pos] == '\n' {
+				p.pos++
+			}
+		case 'x':
+			r, err := p.hexEscape(2)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		case 'u':
+			r, err := p.hexEscape(4)
+			if err != nil {
+				return "", err
+			}
+			if utf16.IsSurrogate(r) {
+				// A UTF-16 surrogate pair, such as \uD83D\uDE00
+				if !strings.HasPrefix(p.text[p.pos:], "\\u") {
+					return "", p.errorf("unpaired surrogate in \\u escape")
+				}
+				p.pos += 2
+				low, err := p.hexEscape(4)
+				if err != nil {
+					return "", err
+				}
+				if r = utf16.DecodeRune(r, low); r == unicode.ReplacementChar {
+					return "", p.errorf("unpaired surrogate in \\u escape")
+				}
+			}
+			sb.WriteRune(r)
+		default:
+			// \\, \", \', \/ and any other character stand for themselves
+			sb.WriteByte(e)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// hexEscape reads the n hex digits of a \x or \u escape.
+func (p *json5Parser) hexEscape(n int) (rune, error) {
+	if p.pos+n > len(p.text) {
+		return 0, p.errorf("truncated escape")
+	}
+	r, err := strconv.ParseUint(p.text[p.pos:p.pos+n], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape %q", p.text[p.pos:p.pos+n])
+	}
+	p.pos += n
+	return rune(r), nil
+}
+
+func (p *json5Parser) number() (json.Number, error) {
+	start := p.pos
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		if c == ',' || c == '}' || c == ']' || c == '/' || strings.IndexByte(" \t\r\n\v\f", c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	lit := p.text[start:p.pos]
+
+	sign, digits := "", strings.TrimPrefix(lit, "+")
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if digits == "Infinity" || digits == "NaN" {
+		return "", p.errorf("%s has no JSON representation", lit)
+	}
+
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		n, err := strconv.ParseUint(digits[2:], 16, 64)
+		if err != nil {
+			return "", p.errorf("invalid number %q", lit)
+		}
+		return json.Number(sign + strconv.FormatUint(n, 10)), nil
+	}
+
+	if !json5Decimal.MatchString(digits) {
+		return "", p.errorf("invalid number %q", lit)
+	}
+	if strings.ContainsAny(digits, ".eE") {
+		// Normalize forms like ".5" and "5." that JSON does not accept
+		f, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return "", p.errorf("invalid number %q", lit)
+		}
+		return json.Number(sign + strconv.FormatFloat(f, 'g', -1, 64)), nil
+	}
+
+	// JSON does not allow leading zeros
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	return json.Number(sign + digits), nil
+}
+
+// json5Decimal matches an unsigned JSON5 decimal literal.
+var json5Decimal = regexp.MustCompile(`^([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
@@ -1,63 +1,302 @@
 package zenoh
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
-// matchKeyExpr checks if pattern matches subject.
-// Supports * (single chunk) and ** (any chunks) wildcards.
-func matchKeyExpr(pattern, subject KeyExpr) bool {
-	p := string(pattern)
-	s := string(subject)
+// Validate checks that k is a valid key expression in canonical form:
+// non-empty chunks separated by '/', no '#' or '?', wildcards only as
+// whole "*" and "**" chunks or as "$*" within a chunk, and none in
+// verbatim chunks, which start with '@'.
+// The error wraps ErrInvalidKeyExpr and gives the reason.
+func (k KeyExpr) Validate() error {
+	if k == "" {
+		return invalidKeyExpr(k, "empty key expression")
+	}
+	if strings.HasPrefix(string(k), "/") {
+		return invalidKeyExpr(k, "leading slash")
+	}
+	if strings.HasSuffix(string(k), "/") {
+		return invalidKeyExpr(k, "trailing slash")
+	}
+	for _, chunk := range strings.Split(string(k), "/") {
+		if err := validateChunk(k, chunk); err != nil {
+			return err
+		}
+	}
+	if c := canonize(k); c != k {
+		return invalidKeyExpr(k, fmt.Sprintf("not canonical, Canonize gives %q", c))
+	}
+	return nil
+}
 
-	// Exact match
-	if p == s {
-		return true
+// Canonize returns the canonical form of k, which zenoh requires:
+// "**/**" becomes "**", "**/*" becomes "*/**", "$*$*" becomes "$*"
+// and a "$*" chunk becomes "*". It fails like Validate for key
+// expressions that cannot be made valid.
+func (k KeyExpr) Canonize() (KeyExpr, error) {
+	c := canonize(k)
+	if err := c.Validate(); err != nil {
+		return "", err
 	}
+	return c, nil
+}
 
-	// ** matches everything
-	if p == "**" {
-		return true
+// canonize applies the canonization rewrites, without validating.
+func canonize(k KeyExpr) KeyExpr {
+	chunks := strings.Split(string(k), "/")
+	out := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		for strings.Contains(chunk, "$*$*") {
+			chunk = strings.ReplaceAll(chunk, "$*$*", "$*")
+		}
+		if chunk == "$*" {
+			chunk = "*"
+		}
+
+		last := len(out) - 1
+		switch {
+		case chunk == "**" && last >= 0 && out[last] == "**":
+			// **/** matches the same keys as **
+		case chunk == "*" && last >= 0 && out[last] == "**":
+			// Move single stars before a double star
+			out[last] = "*"
+			out = append(out, "**")
+		default:
+			out = append(out, chunk)
+		}
 	}
+	return KeyExpr(strings.Join(out, "/"))
+}
 
-	// Simple wildcard matching
-	pParts := strings.Split(p, "/")
-	sParts := strings.Split(s, "/")
+// validateChunk checks the characters and wildcards of a single chunk.
+func validateChunk(k KeyExpr, chunk string) error {
+	switch {
+	case chunk == "":
+		return invalidKeyExpr(k, "empty chunk")
+	case chunk == "*" || chunk == "**":
+		return nil
+	case strings.ContainsAny(chunk, "#?"):
+		return invalidKeyExpr(k, fmt.Sprintf("forbidden character in chunk %q", chunk))
+	case strings.Contains(chunk, "**"):
+		return invalidKeyExpr(k, fmt.Sprintf("** must be a whole chunk, got %q", chunk))
+	case verbatim(chunk) && strings.Contains(chunk, "$*"):
+		return invalidKeyExpr(k, fmt.Sprintf("verbatim chunk %q cannot hold wildcards", chunk))
+	}
 
-	return matchParts(pParts, sParts)
+	// Within a chunk, wildcards are written $* and $ only starts one
+	for i := 0; i < len(chunk); i++ {
+		switch chunk[i] {
+		case '$':
+			if i+1 == len(chunk) || chunk[i+1] != '*' {
+				return invalidKeyExpr(k, fmt.Sprintf("$ must be followed by * in chunk %q", chunk))
+			}
+			i++
+		case '*':
+			return invalidKeyExpr(k, fmt.Sprintf("* must be a whole chunk or written $*, got %q", chunk))
+		}
+	}
+	return nil
 }
 
-func matchParts(pattern, subject []string) bool {
-	pi, si := 0, 0
+// invalidKeyExpr returns an ErrInvalidKeyExpr error with a reason.
+func invalidKeyExpr(k KeyExpr, reason string) error {
+	return fmt.Errorf("%w: %q: %s", ErrInvalidKeyExpr, k, reason)
+}
 
-	for pi < len(pattern) && si < len(subject) {
-		p := pattern[pi]
+// Intersects reports whether at least one key matches both a and b,
+// as z_keyexpr_intersects does. Both should be valid (see Validate).
+//
+// "*" matches one chunk and "**" any number of chunks, including none.
+// Within a chunk, "$*" matches any characters, so "joint_$*" matches
+// "joint_7". Wildcards never match verbatim chunks, those starting with '@'.
+func Intersects(a, b KeyExpr) bool {
+	ac, bc := chunks(a), chunks(b)
+	n, m := len(ac), len(bc)
 
-		switch p {
-		case "**":
-			// ** at end matches everything
-			if pi == len(pattern)-1 {
-				return true
+	// dp[i][j] reports whether ac[i:] and bc[j:] intersect
+	dp := newMatchTable(n, m)
+	dp[n][m] = true
+	for i := n - 1; i >= 0; i-- {
+		dp[i][m] = ac[i] == "**" && dp[i+1][m]
+	}
+	for j := m - 1; j >= 0; j-- {
+		dp[n][j] = bc[j] == "**" && dp[n][j+1]
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case ac[i] == "**":
+				dp[i][j] = dp[i+1][j] || (!verbatim(bc[j]) && dp[i][j+1])
+			case bc[j] == "**":
+				dp[i][j] = dp[i][j+1] || (!verbatim(ac[i]) && dp[i+1][j])
+			default:
+				dp[i][j] = chunkIntersects(ac[i], bc[j]) && dp[i+1][j+1]
 			}
-			// Try matching rest of pattern at each position
-			for i := si; i <= len(subject); i++ {
-				if matchParts(pattern[pi+1:], subject[i:]) {
-					return true
-				}
+		}
+	}
+	return dp[0][0]
+}
+
+// Includes reports whether every key matching b also matches a,
+// as z_keyexpr_includes does. Both should be valid (see Validate).
+func Includes(a, b KeyExpr) bool {
+	ac, bc := chunks(a), chunks(b)
+	n, m := len(ac), len(bc)
+
+	// dp[i][j] reports whether ac[i:] includes bc[j:]
+	dp := newMatchTable(n, m)
+	dp[n][m] = true
+	for i := n - 1; i >= 0; i-- {
+		dp[i][m] = ac[i] == "**" && dp[i+1][m]
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case ac[i] == "**":
+				dp[i][j] = dp[i+1][j] || (!verbatim(bc[j]) && dp[i][j+1])
+			case bc[j] == "**":
+				// Only ** includes the chunk sequences ** stands for
+				dp[i][j] = false
+			default:
+				dp[i][j] = chunkIncludes(ac[i], bc[j]) && dp[i+1][j+1]
 			}
-			return false
-		case "*":
-			// * matches single chunk
-			pi++
-			si++
-		default:
-			// Exact match required
-			if p != subject[si] {
-				return false
+		}
+	}
+	return dp[0][0]
+}
+
+// Join returns a/b in canonical form, as z_keyexpr_join does.
+func Join(a, b KeyExpr) (KeyExpr, error) {
+	if err := a.Validate(); err != nil {
+		return "", err
+	}
+	if err := b.Validate(); err != nil {
+		return "", err
+	}
+	return (a + "/" + b).Canonize()
+}
+
+// Concat appends s to a without a separator, as z_keyexpr_concat does,
+// e.g. Concat("robot/joint_", "7"). It fails if a ends and s starts with
+// '*', which could form an unintended "**"; use Join for whole chunks.
+func Concat(a KeyExpr, s string) (KeyExpr, error) {
+	if err := a.Validate(); err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(string(a), "*") && strings.HasPrefix(s, "*") {
+		return "", invalidKeyExpr(a+KeyExpr(s), "concat would join * wildcards")
+	}
+	return (a + KeyExpr(s)).Canonize()
+}
+
+// chunks splits a key expression into its chunks.
+func chunks(k KeyExpr) []string {
+	return strings.Split(string(k), "/")
+}
+
+// newMatchTable allocates an (n+1)×(m+1) table for matching sequences
+// of chunks or characters.
+func newMatchTable(n, m int) [][]bool {
+	cells := make([]bool, (n+1)*(m+1))
+	dp := make([][]bool, n+1)
+	for i := range dp {
+		dp[i] = cells[i*(m+1) : (i+1)*(m+1)]
+	}
+	return dp
+}
+
+// verbatim reports whether a chunk only matches itself.
+func verbatim(chunk string) bool {
+	return strings.HasPrefix(chunk, "@")
+}
+
+// chunkIntersects reports whether two chunks, neither "**", can match
+// the same chunk.
+func chunkIntersects(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if verbatim(a) != verbatim(b) {
+		return false
+	}
+	if a == "*" || b == "*" {
+		return true
+	}
+	// Distinct literal chunks never intersect; only build the table
+	// when a chunk has a "$*" wildcard
+	if !strings.Contains(a, "$*") && !strings.Contains(b, "$*") {
+		return false
+	}
+	ga, gb := chunkGlob(a), chunkGlob(b)
+	n, m := len(ga), len(gb)
+
+	// dp[i][j] reports whether ga[i:] and gb[j:] match a common string
+	dp := newMatchTable(n, m)
+	dp[n][m] = true
+	for i := n - 1; i >= 0; i-- {
+		dp[i][m] = ga[i] == '*' && dp[i+1][m]
+	}
+	for j := m - 1; j >= 0; j-- {
+		dp[n][j] = gb[j] == '*' && dp[n][j+1]
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case ga[i] == '*':
+				dp[i][j] = dp[i+1][j] || dp[i][j+1]
+			case gb[j] == '*':
+				dp[i][j] = dp[i][j+1] || dp[i+1][j]
+			default:
+				dp[i][j] = ga[i] == gb[j] && dp[i+1][j+1]
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+// chunkIncludes reports whether chunk a, which is not "**", matches
+// every chunk that b matches.
+func chunkIncludes(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if verbatim(a) != verbatim(b) {
+		return false
+	}
+	if a == "*" {
+		return true
+	}
+	// A literal chunk only includes itself, and only "*" includes "*"
+	if !strings.Contains(a, "$*") || b == "*" {
+		return false
+	}
+	ga, gb := chunkGlob(a), chunkGlob(b)
+	n, m := len(ga), len(gb)
+
+	// dp[i][j] reports whether ga[i:] matches every string gb[j:] matches;
+	// a wildcard of b can only be covered by one of a
+	dp := newMatchTable(n, m)
+	dp[n][m] = true
+	for i := n - 1; i >= 0; i-- {
+		dp[i][m] = ga[i] == '*' && dp[i+1][m]
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if ga[i] == '*' {
+				dp[i][j] = dp[i+1][j] || dp[i][j+1]
+			} else {
+				dp[i][j] = gb[j] != '*' && ga[i] == gb[j] && dp[i+1][j+1]
 			}
-			pi++
-			si++
 		}
 	}
+	return dp[0][0]
+}
 
-	// Check if both exhausted
-	return pi == len(pattern) && si == len(subject)
+// chunkGlob returns a chunk with each "$*" as a single '*', which then
+// matches any characters. A "*" chunk is already in this form; '*' is
+// not allowed elsewhere in valid chunks.
+func chunkGlob(chunk string) string {
+	return strings.ReplaceAll(chunk, "$*", "*")
 }
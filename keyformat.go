@@ -0,0 +1,271 @@
+package zenoh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeyFormat is a key expression template with named captures, in
+// Zenoh's keyformat syntax:
+//
+//	reachy_mini/${robot_id:*}/joint/${name:*}
+//
+// Each ${id:pattern} spec stands for a part of the key matching pattern,
+// a key expression fragment such as "*", "**" or "$*". A default value
+// may follow '#': ${name:*#head}. Within a capture, "**" matches one or
+// more chunks; a ${rest:**} capture standing for whole chunks may also
+// match none, as in KeyExpr, and then captures "".
+//
+// Example:
+//
+//	f, _ := zenoh.NewKeyFormat("reachy_mini/${robot_id:*}/joint/${name:*}")
+//	key, _ := f.Format(map[string]string{"robot_id": "r1", "name": "neck"})
+//	sub, _ := session.Subscribe(f.KeyExpr(), func(s zenoh.Sample) {
+//	    captures, err := f.Parse(s.KeyExpr)
+//	    ...
+//	})
+type KeyFormat struct {
+	format   string
+	segments []keyFormatSegment
+	keyExpr  KeyExpr
+	re       *regexp.Regexp
+}
+
+// keyFormatSegment is literal text, or a spec if id is set.
+type keyFormatSegment struct {
+	literal string
+
+	id         string
+	pattern    string
+	def        string
+	hasDefault bool
+	re         *regexp.Regexp
+
+	// sepBefore and sepAfter mark a "**" capture that owns the '/'
+	// before or after it, written only if the capture is not empty.
+	sepBefore bool
+	sepAfter  bool
+}
+
+// keyFormatID is the syntax of capture names.
+var keyFormatID = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewKeyFormat parses a key format. It fails if a spec is malformed,
+// a name is used twice, or the format does not yield a valid KeyExpr.
+func NewKeyFormat(format string) (KeyFormat, error) {
+	f := KeyFormat{format: format}
+	seen := make(map[string]bool)
+
+	for rest := format; rest != ""; {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			start = len(rest)
+		}
+		if start > 0 {
+			f.segments = append(f.segments, keyFormatSegment{literal: rest[:start]})
+			rest = rest[start:]
+			continue
+		}
+
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return KeyFormat{}, invalidKeyFormat(format, "unterminated ${")
+		}
+		seg, err := parseKeyFormatSpec(rest[2:end])
+		if err != nil {
+			return KeyFormat{}, invalidKeyFormat(format, err.Error())
+		}
+		if seen[seg.id] {
+			return KeyFormat{}, invalidKeyFormat(format, fmt.Sprintf("duplicate capture %q", seg.id))
+		}
+		seen[seg.id] = true
+
+		f.segments = append(f.segments, seg)
+		rest = rest[end+1:]
+	}
+	absorbSeparators(f.segments)
+
+	var wildcard, expr strings.Builder
+	expr.WriteString("^")
+	for _, seg := range f.segments {
+		chunkStart := wildcard.Len() == 0 || strings.HasSuffix(wildcard.String(), "/")
+		switch {
+		case seg.id == "":
+			wildcard.WriteString(seg.literal)
+			expr.WriteString(fragmentRegexp(seg.literal, chunkStart))
+		case seg.sepBefore:
+			wildcard.WriteString("/" + seg.pattern)
+			expr.WriteString("(?:/(" + fragmentRegexp(seg.pattern, true) + "))?")
+		case seg.sepAfter:
+			wildcard.WriteString(seg.pattern + "/")
+			expr.WriteString("(?:(" + fragmentRegexp(seg.pattern, true) + ")/)?")
+		default:
+			wildcard.WriteString(seg.pattern)
+			expr.WriteString("(" + fragmentRegexp(seg.pattern, chunkStart) + ")")
+		}
+	}
+	expr.WriteString("$")
+
+	keyExpr, err := KeyExpr(wildcard.String()).Canonize()
+	if err != nil {
+		return KeyFormat{}, invalidKeyFormat(format, err.Error())
+	}
+	f.keyExpr = keyExpr
+	f.re = regexp.MustCompile(expr.String())
+	return f, nil
+}
+
+// absorbSeparators moves the '/' next to each "**" capture out of the
+// neighbouring literal and into the capture, so that, like "**" in
+// KeyExpr, the capture may match zero chunks: the key then has neither
+// the chunks nor the separator, and the capture is "".
+func absorbSeparators(segments []keyFormatSegment) {
+	for i := range segments {
+		seg := &segments[i]
+		if seg.id == "" || seg.pattern != "**" {
+			continue
+		}
+		if i > 0 && strings.HasSuffix(segments[i-1].literal, "/") {
+			segments[i-1].literal = strings.TrimSuffix(segments[i-1].literal, "/")
+			seg.sepBefore = true
+		} else if i == 0 && len(segments) > 1 && strings.HasPrefix(segments[1].literal, "/") {
+			segments[1].literal = strings.TrimPrefix(segments[1].literal, "/")
+			seg.sepAfter = true
+		}
+	}
+}
+
+// parseKeyFormatSpec parses the inside of ${...}: id:pattern#default.
+func parseKeyFormatSpec(spec string) (keyFormatSegment, error) {
+	id, pattern, ok := strings.Cut(spec, ":")
+	if !ok {
+		return keyFormatSegment{}, fmt.Errorf("spec ${%s} has no pattern", spec)
+	}
+	if !keyFormatID.MatchString(id) {
+		return keyFormatSegment{}, fmt.Errorf("invalid capture name %q", id)
+	}
+
+	seg := keyFormatSegment{id: id}
+	seg.pattern, seg.def, seg.hasDefault = strings.Cut(pattern, "#")
+	if seg.pattern == "" {
+		return keyFormatSegment{}, fmt.Errorf("capture %q has an empty pattern", id)
+	}
+	seg.re = regexp.MustCompile("^" + fragmentRegexp(seg.pattern, false) + "$")
+	if seg.hasDefault && !seg.re.MatchString(seg.def) {
+		return keyFormatSegment{}, fmt.Errorf("default %q of capture %q does not match %q", seg.def, id, seg.pattern)
+	}
+	return seg, nil
+}
+
+// fragmentRegexp converts a key expression fragment into a regular
+// expression matching the keys it matches. Wildcards never match a
+// verbatim chunk, one starting with '@', so a "$*" that starts a chunk
+// does not match a leading '@'; chunkStart reports whether the fragment
+// starts a chunk of the key.
+func fragmentRegexp(fragment string, chunkStart bool) string {
+	chunks := strings.Split(fragment, "/")
+	for i, chunk := range chunks {
+		switch chunk {
+		case "**":
+			chunks[i] = `[^/@][^/]*(?:/[^/@][^/]*)*`
+		case "*":
+			chunks[i] = `[^/@][^/]*`
+		default:
+			parts := strings.Split(chunk, "$*")
+			for j, p := range parts {
+				parts[j] = regexp.QuoteMeta(p)
+			}
+			re := strings.Join(parts, `[^/]*`)
+			if (i > 0 || chunkStart) && len(parts) > 1 && parts[0] == "" {
+				// The chunk starts with "$*": it may match nothing only
+				// if the text after it does not start with '@'
+				lead := `[^/@][^/]*`
+				if !strings.HasPrefix(parts[1], "@") {
+					lead = "(?:" + lead + ")?"
+				}
+				re = lead + strings.Join(parts[1:], `[^/]*`)
+			}
+			chunks[i] = re
+		}
+	}
+	return strings.Join(chunks, "/")
+}
+
+// String returns the format as given to NewKeyFormat.
+func (f KeyFormat) String() string {
+	return f.format
+}
+
+// KeyExpr returns the key expression matching every key of the format,
+// with each spec replaced by its pattern, for subscribing.
+func (f KeyFormat) KeyExpr() KeyExpr {
+	return f.keyExpr
+}
+
+// Format builds a key from named values. Captures missing from values
+// take their default; each value must match its pattern.
+func (f KeyFormat) Format(values map[string]string) (KeyExpr, error) {
+	var b strings.Builder
+	for _, seg := range f.segments {
+		if seg.id == "" {
+			b.WriteString(seg.literal)
+			continue
+		}
+
+		value, ok := values[seg.id]
+		if !ok {
+			if !seg.hasDefault {
+				return "", fmt.Errorf("%w: format %q: missing value for %q", ErrInvalidKeyExpr, f.format, seg.id)
+			}
+			value = seg.def
+		}
+		if value == "" && (seg.sepBefore || seg.sepAfter) {
+			continue
+		}
+		if !seg.re.MatchString(value) {
+			return "", fmt.Errorf("%w: format %q: value %q of %q does not match %q", ErrInvalidKeyExpr, f.format, value, seg.id, seg.pattern)
+		}
+		switch {
+		case seg.sepBefore:
+			b.WriteString("/" + value)
+		case seg.sepAfter:
+			b.WriteString(value + "/")
+		default:
+			b.WriteString(value)
+		}
+	}
+
+	key := KeyExpr(b.String())
+	if err := key.Validate(); err != nil {
+		return "", err
+	}
+	if !Includes(f.keyExpr, key) {
+		return "", fmt.Errorf("%w: format %q: %q does not match %q", ErrInvalidKeyExpr, f.format, key, f.keyExpr)
+	}
+	return key, nil
+}
+
+// Parse returns the captures of a key built with the format, such as
+// Sample.KeyExpr. It fails if the key does not match the format.
+func (f KeyFormat) Parse(key KeyExpr) (map[string]string, error) {
+	m := f.re.FindStringSubmatch(string(key))
+	if m == nil {
+		return nil, fmt.Errorf("%w: %q does not match format %q", ErrInvalidKeyExpr, key, f.format)
+	}
+
+	captures := make(map[string]string)
+	i := 1
+	for _, seg := range f.segments {
+		if seg.id != "" {
+			captures[seg.id] = m[i]
+			i++
+		}
+	}
+	return captures, nil
+}
+
+// invalidKeyFormat returns an ErrInvalidKeyExpr error for a bad format.
+func invalidKeyFormat(format, reason string) error {
+	return fmt.Errorf("%w: format %q: %s", ErrInvalidKeyExpr, format, reason)
+}
@@ -0,0 +1,256 @@
+package zenoh
+
+import (
+	"iter"
+	"strings"
+)
+
+// KeyExprTree indexes values by key expression, wildcards included, and
+// finds the ones whose key expression matches a key. Lookups walk the
+// key's chunks once instead of comparing it with every key expression,
+// and do not allocate for concrete keys.
+//
+// The zero value is an empty tree. A KeyExprTree is not safe for
+// concurrent use; that includes Match, which marks the nodes it visits.
+type KeyExprTree[V comparable] struct {
+	root  *keyExprNode[V]
+	epoch uint64
+	size  int
+}
+
+// keyExprNode is the node reached by a sequence of chunks.
+type keyExprNode[V comparable] struct {
+	// children are indexed by chunk, wildcards included;
+	// subChunk lists the children whose chunk contains "$*".
+	children map[string]*keyExprNode[V]
+	subChunk []*keyExprNode[V]
+
+	chunk   string
+	pattern KeyExpr
+	values  []V
+
+	// seen is the epoch of the last Match that reached the node, so
+	// values reachable by several paths are yielded once.
+	seen uint64
+}
+
+// Insert adds v under the key expression pattern, which must be valid.
+// The same pattern can hold several values.
+func (t *KeyExprTree[V]) Insert(pattern KeyExpr, v V) error {
+	if err := pattern.Validate(); err != nil {
+		return err
+	}
+	if t.root == nil {
+		t.root = &keyExprNode[V]{}
+	}
+
+	n := t.root
+	for _, chunk := range chunks(pattern) {
+		child := n.children[chunk]
+		if child == nil {
+			child = &keyExprNode[V]{chunk: chunk}
+			if n.children == nil {
+				n.children = make(map[string]*keyExprNode[V])
+			}
+			n.children[chunk] = child
+			if strings.Contains(chunk, "$*") {
+				n.subChunk = append(n.subChunk, child)
+			}
+		}
+		n = child
+	}
+	n.pattern = pattern
+	n.values = append(n.values, v)
+	t.size++
+	return nil
+}
+
+// Remove removes one occurrence of v under pattern and reports whether
+// it was found.
+func (t *KeyExprTree[V]) Remove(pattern KeyExpr, v V) bool {
+	if t.root == nil {
+		return false
+	}
+	found, _ := t.root.remove(chunks(pattern), v)
+	if found {
+		t.size--
+	}
+	return found
+}
+
+// remove removes v below n and reports whether it was found, and
+// whether n is left empty and can be pruned.
+func (n *keyExprNode[V]) remove(path []string, v V) (found, empty bool) {
+	if len(path) == 0 {
+		for i, other := range n.values {
+			if other == v {
+				n.values = append(n.values[:i], n.values[i+1:]...)
+				found = true
+				break
+			}
+		}
+	} else if child := n.children[path[0]]; child != nil {
+		var prune bool
+		found, prune = child.remove(path[1:], v)
+		if prune {
+			delete(n.children, path[0])
+			for i, c := range n.subChunk {
+				if c == child {
+					n.subChunk = append(n.subChunk[:i], n.subChunk[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return found, len(n.values) == 0 && len(n.children) == 0
+}
+
+// Len returns the number of values in the tree.
+func (t *KeyExprTree[V]) Len() int {
+	return t.size
+}
+
+// All returns an iterator over the key expressions and values of the tree.
+func (t *KeyExprTree[V]) All() iter.Seq2[KeyExpr, V] {
+	return func(yield func(KeyExpr, V) bool) {
+		if t.root != nil {
+			t.root.all(yield)
+		}
+	}
+}
+
+func (n *keyExprNode[V]) all(yield func(KeyExpr, V) bool) bool {
+	for _, v := range n.values {
+		if !yield(n.pattern, v) {
+			return false
+		}
+	}
+	for _, child := range n.children {
+		if !child.all(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match calls yield for each value whose key expression intersects key,
+// until yield returns false. Each value is yielded once.
+//
+// Keys containing wildcards are compared with every key expression of
+// the tree using Intersects.
+func (t *KeyExprTree[V]) Match(key KeyExpr, yield func(V) bool) {
+	if t.root == nil {
+		return
+	}
+	t.epoch++
+
+	if strings.Contains(string(key), "*") {
+		t.root.all(func(pattern KeyExpr, v V) bool {
+			return !Intersects(pattern, key) || yield(v)
+		})
+		return
+	}
+	t.root.match(string(key), 0, t.epoch, yield)
+}
+
+// match walks the chunks of key from pos, which is past the end once
+// every chunk is consumed. It returns false once yield does.
+func (n *keyExprNode[V]) match(key string, pos int, epoch uint64, yield func(V) bool) bool {
+	if pos > len(key) {
+		if !n.yield(epoch, yield) {
+			return false
+		}
+		// ** also matches no chunk at all
+		if d := n.children["**"]; d != nil {
+			return d.match(key, pos, epoch, yield)
+		}
+		return true
+	}
+
+	chunk, next := nextChunk(key, pos)
+	if c := n.children[chunk]; c != nil {
+		if !c.match(key, next, epoch, yield) {
+			return false
+		}
+	}
+	if !verbatim(chunk) {
+		if c := n.children["*"]; c != nil {
+			if !c.match(key, next, epoch, yield) {
+				return false
+			}
+		}
+		for _, c := range n.subChunk {
+			if subChunkMatch(c.chunk, chunk) && !c.match(key, next, epoch, yield) {
+				return false
+			}
+		}
+	}
+
+	// ** consumes any number of chunks, up to a verbatim one
+	if d := n.children["**"]; d != nil {
+		for p := pos; ; {
+			if !d.match(key, p, epoch, yield) {
+				return false
+			}
+			if p > len(key) {
+				break
+			}
+			c, nextP := nextChunk(key, p)
+			if verbatim(c) {
+				break
+			}
+			p = nextP
+		}
+	}
+	return true
+}
+
+// yield yields the values of n, unless this Match already did.
+func (n *keyExprNode[V]) yield(epoch uint64, yield func(V) bool) bool {
+	if n.seen == epoch {
+		return true
+	}
+	n.seen = epoch
+	for _, v := range n.values {
+		if !yield(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextChunk returns the chunk of key starting at pos and the start of
+// the following one, which is past the end for the last chunk.
+func nextChunk(key string, pos int) (string, int) {
+	if i := strings.IndexByte(key[pos:], '/'); i >= 0 {
+		return key[pos : pos+i], pos + i + 1
+	}
+	return key[pos:], len(key) + 1
+}
+
+// subChunkMatch reports whether a concrete chunk matches a chunk
+// pattern containing "$*" wildcards, without allocating.
+func subChunkMatch(pattern, chunk string) bool {
+	p, c := 0, 0
+	star, retry := -1, 0
+	for c < len(chunk) {
+		switch {
+		case strings.HasPrefix(pattern[p:], "$*"):
+			// Try matching nothing first; backtrack to take more
+			p += 2
+			star, retry = p, c
+		case p < len(pattern) && pattern[p] == chunk[c]:
+			p++
+			c++
+		case star >= 0:
+			retry++
+			p, c = star, retry
+		default:
+			return false
+		}
+	}
+	for strings.HasPrefix(pattern[p:], "$*") {
+		p += 2
+	}
+	return p == len(pattern)
+}
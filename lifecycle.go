@@ -0,0 +1,47 @@
+package zenoh
+
+import "sync"
+
+// inFlight tracks the calls using an entity, such as subscriber
+// callbacks or publisher puts, so that closing the entity can wait for
+// them before its native resources are released.
+//
+// Calls may nest on one goroutine, as when a handler publishes to its
+// own key; close must not be called from within a call, since it would
+// wait for itself.
+type inFlight struct {
+	mu     sync.Mutex
+	closed bool
+	calls  sync.WaitGroup
+}
+
+// enter registers a call. It returns false once close was called, in
+// which case the call must not use the entity; otherwise the caller
+// must call exit when done.
+func (f *inFlight) enter() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return false
+	}
+	f.calls.Add(1)
+	return true
+}
+
+// exit ends a call registered by enter.
+func (f *inFlight) exit() {
+	f.calls.Done()
+}
+
+// close refuses new calls and waits for the running ones. It reports
+// whether this was the first close; later ones wait the same way.
+func (f *inFlight) close() bool {
+	f.mu.Lock()
+	first := !f.closed
+	f.closed = true
+	f.mu.Unlock()
+
+	f.calls.Wait()
+	return first
+}
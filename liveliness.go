@@ -0,0 +1,21 @@
+package zenoh
+
+// LivelinessToken advertises that this session is alive on a key expression.
+//
+// Tokens are created via Session.DeclareLivelinessToken(). Liveliness
+// subscribers on intersecting key expressions receive a PUT sample when the
+// token appears and a DELETE sample when it is closed or its session goes
+// away (including when the process dies or loses connectivity).
+//
+// Example:
+//
+//	token, err := session.DeclareLivelinessToken("reachy_mini/alive/controller")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer token.Close()
+type LivelinessToken interface {
+	// Close undeclares the token.
+	// Liveliness subscribers receive a DELETE sample for its key expression.
+	Close() error
+}
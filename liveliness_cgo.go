@@ -0,0 +1,51 @@
+//go:build cgo
+
+package zenoh
+
+/*
+#include <zenoh.h>
+*/
+import "C"
+
+// cgoLivelinessToken wraps a native Zenoh liveliness token.
+type cgoLivelinessToken struct {
+	session *cgoSession
+	keyExpr KeyExpr
+	token   C.z_owned_liveliness_token_t
+	closed  bool
+
+	// declared is the declared key expression it was declared with,
+	// if any, referenced until it is dropped.
+	declared *cgoKeyExpr
+}
+
+func (t *cgoLivelinessToken) Close() error {
+	t.session.mu.Lock()
+	if t.closed || t.session.closed {
+		// Already released, possibly by session.Close()
+		t.closed = true
+		t.session.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+
+	// Remove from session so session.Close() does not drop it again
+	for i, other := range t.session.tokens {
+		if other == t {
+			t.session.tokens = append(t.session.tokens[:i], t.session.tokens[i+1:]...)
+			break
+		}
+	}
+
+	// Drop without the lock, since same-session liveliness subscribers
+	// are notified on this thread; session.Close() waits for the call
+	t.session.calls.enter()
+	t.session.mu.Unlock()
+	defer t.session.calls.exit()
+	defer t.session.releaseRetained(t.keyExpr, t.declared)
+
+	// Dropping the token undeclares it
+	C.z_liveliness_token_drop(C.z_liveliness_token_move(&t.token))
+
+	return nil
+}
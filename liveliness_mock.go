@@ -0,0 +1,212 @@
+//go:build !cgo
+
+package zenoh
+
+import (
+	"sync"
+	"time"
+)
+
+// mockLiveliness is shared by every mock session in the process, so a
+// token declared by one session is seen by the others, as through a router.
+var mockLiveliness = &mockLivelinessRegistry{alive: make(map[KeyExpr]int)}
+
+// mockLivelinessRegistry tracks liveliness tokens and subscribers.
+// A key is alive while at least one token is declared on it.
+type mockLivelinessRegistry struct {
+	mu          sync.Mutex
+	tokens      []*mockLivelinessToken
+	subscribers []*mockLivelinessSubscriber
+	alive       map[KeyExpr]int
+}
+
+// mockLivelinessToken implements LivelinessToken for testing.
+type mockLivelinessToken struct {
+	session *mockSession
+	keyExpr KeyExpr
+}
+
+// mockLivelinessSubscriber implements Subscriber for liveliness changes.
+type mockLivelinessSubscriber struct {
+	session *mockSession
+	keyExpr KeyExpr
+	handler Handler
+
+	// calls tracks the notifications in progress, entered while the
+	// registry lock is held.
+	calls inFlight
+}
+
+func (t *mockLivelinessToken) Close() error {
+	mockLiveliness.undeclareToken(t)
+	return nil
+}
+
+func (s *mockLivelinessSubscriber) Close() error {
+	mockLiveliness.mu.Lock()
+	mockLiveliness.removeSubscriber(s)
+	mockLiveliness.mu.Unlock()
+
+	// Wait for notifications already collected, like the cgo backend
+	s.calls.close()
+	return nil
+}
+
+func (r *mockLivelinessRegistry) declareToken(t *mockLivelinessToken) {
+	r.mu.Lock()
+	r.tokens = append(r.tokens, t)
+	r.alive[t.keyExpr]++
+	var subscribers []*mockLivelinessSubscriber
+	if r.alive[t.keyExpr] == 1 {
+		subscribers = r.matchingSubscribers(t.keyExpr)
+	}
+	r.mu.Unlock()
+
+	notifyLiveliness(subscribers, t.keyExpr, SampleKindPut)
+}
+
+func (r *mockLivelinessRegistry) undeclareToken(t *mockLivelinessToken) {
+	r.mu.Lock()
+	subscribers, removed := r.removeToken(t)
+	r.mu.Unlock()
+
+	if removed {
+		notifyLiveliness(subscribers, t.keyExpr, SampleKindDelete)
+	}
+}
+
+// removeToken removes t and returns the subscribers to notify if its
+// key is no longer alive. Must be called with r.mu held.
+func (r *mockLivelinessRegistry) removeToken(t *mockLivelinessToken) ([]*mockLivelinessSubscriber, bool) {
+	for i, other := range r.tokens {
+		if other != t {
+			continue
+		}
+		r.tokens = append(r.tokens[:i], r.tokens[i+1:]...)
+		r.alive[t.keyExpr]--
+		if r.alive[t.keyExpr] > 0 {
+			return nil, false
+		}
+		delete(r.alive, t.keyExpr)
+		return r.matchingSubscribers(t.keyExpr), true
+	}
+	return nil, false
+}
+
+func (r *mockLivelinessRegistry) subscribe(s *mockLivelinessSubscriber, history bool) {
+	r.mu.Lock()
+	r.subscribers = append(r.subscribers, s)
+	var existing []KeyExpr
+	if history {
+		existing = r.aliveKeys(s.keyExpr)
+	}
+	for range existing {
+		s.calls.enter()
+	}
+	r.mu.Unlock()
+
+	for _, keyExpr := range existing {
+		notifyLiveliness([]*mockLivelinessSubscriber{s}, keyExpr, SampleKindPut)
+	}
+}
+
+// removeSubscriber must be called with r.mu held.
+func (r *mockLivelinessRegistry) removeSubscriber(s *mockLivelinessSubscriber) {
+	for i, other := range r.subscribers {
+		if other == s {
+			r.subscribers = append(r.subscribers[:i], r.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// get returns a PUT sample for every alive key matching keyExpr.
+func (r *mockLivelinessRegistry) get(keyExpr KeyExpr) []Sample {
+	r.mu.Lock()
+	keys := r.aliveKeys(keyExpr)
+	r.mu.Unlock()
+
+	samples := make([]Sample, 0, len(keys))
+	for _, k := range keys {
+		samples = append(samples, Sample{KeyExpr: k, ReceivedAt: time.Now(), Kind: SampleKindPut, QoS: DefaultQoS()})
+	}
+	return samples
+}
+
+// closeSession drops the tokens and subscribers owned by a closing session.
+// Subscribers of other sessions see its tokens disappear.
+func (r *mockLivelinessRegistry) closeSession(session *mockSession) {
+	type change struct {
+		keyExpr     KeyExpr
+		subscribers []*mockLivelinessSubscriber
+	}
+
+	r.mu.Lock()
+	var subscribers, closed []*mockLivelinessSubscriber
+	for _, s := range r.subscribers {
+		if s.session != session {
+			subscribers = append(subscribers, s)
+		} else {
+			closed = append(closed, s)
+		}
+	}
+	r.subscribers = subscribers
+
+	var changes []change
+	for _, t := range append([]*mockLivelinessToken(nil), r.tokens...) {
+		if t.session != session {
+			continue
+		}
+		if subscribers, removed := r.removeToken(t); removed {
+			changes = append(changes, change{t.keyExpr, subscribers})
+		}
+	}
+	r.mu.Unlock()
+
+	for _, s := range closed {
+		s.calls.close()
+	}
+	for _, c := range changes {
+		notifyLiveliness(c.subscribers, c.keyExpr, SampleKindDelete)
+	}
+}
+
+// matchingSubscribers returns the subscribers to notify of a change
+// on keyExpr, each entered for the notification so that Close waits
+// for it. Must be called with r.mu held.
+func (r *mockLivelinessRegistry) matchingSubscribers(keyExpr KeyExpr) []*mockLivelinessSubscriber {
+	var subscribers []*mockLivelinessSubscriber
+	for _, s := range r.subscribers {
+		if Intersects(s.keyExpr, keyExpr) && s.calls.enter() {
+			subscribers = append(subscribers, s)
+		}
+	}
+	return subscribers
+}
+
+// aliveKeys must be called with r.mu held.
+func (r *mockLivelinessRegistry) aliveKeys(keyExpr KeyExpr) []KeyExpr {
+	var keys []KeyExpr
+	for k := range r.alive {
+		if Intersects(keyExpr, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// notifyLiveliness calls the handlers of subscribers entered by
+// matchingSubscribers synchronously, so that a PUT and the matching
+// DELETE are always delivered in order.
+func notifyLiveliness(subscribers []*mockLivelinessSubscriber, keyExpr KeyExpr, kind SampleKind) {
+	sample := Sample{
+		KeyExpr:    keyExpr,
+		ReceivedAt: time.Now(),
+		Kind:       kind,
+		QoS:        DefaultQoS(),
+	}
+	for _, s := range subscribers {
+		s.handler(sample)
+		s.calls.exit()
+	}
+}
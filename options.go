@@ -0,0 +1,126 @@
+package zenoh
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Option configures a single operation such as Get.
+// Options that do not apply to an operation are ignored.
+type Option func(*options)
+
+// options holds the values collected from a list of Option.
+type options struct {
+	parameters string
+	payload    []byte
+	attachment []byte
+	encoding   *Encoding
+	history    bool
+
+	priority          *Priority
+	congestionControl *CongestionControl
+	express           bool
+	reliability       *Reliability
+}
+
+// encodingOr returns the encoding set by WithEncoding, or def if none.
+func (o options) encodingOr(def Encoding) Encoding {
+	if o.encoding != nil {
+		return *o.encoding
+	}
+	return def
+}
+
+// errDeleteAttachment is returned when a DELETE carries an attachment,
+// which the zenoh-c 1.0 delete options cannot express. The mock rejects
+// it too so tests do not pass with code that fails on a real session.
+var errDeleteAttachment = fmt.Errorf("%w: attachment on DELETE: %w", ErrPublishFailed, errors.ErrUnsupported)
+
+// collectOptions applies opts in order and returns the result.
+func collectOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithParameters sets the selector parameters of a query (applies to Get).
+// Parameters use the Zenoh form "key1=value1;key2=value2".
+func WithParameters(parameters string) Option {
+	return func(o *options) {
+		o.parameters = parameters
+	}
+}
+
+// WithPayload attaches a payload to a query (applies to Get).
+func WithPayload(data []byte) Option {
+	return func(o *options) {
+		o.payload = data
+	}
+}
+
+// WithAttachment attaches user metadata such as request IDs or trace
+// context (applies to Put, Get and Query.Reply).
+func WithAttachment(data []byte) Option {
+	return func(o *options) {
+		o.attachment = data
+	}
+}
+
+// WithAttachmentMap attaches key/value metadata encoded with
+// EncodeAttachment (applies to Put, Get and Query.Reply).
+// Receivers decode it with DecodeAttachment.
+func WithAttachmentMap(kv map[string]string) Option {
+	return WithAttachment(EncodeAttachment(kv))
+}
+
+// WithEncoding sets the payload encoding (applies to Put, Get and
+// Query.Reply). Passed to Session.Publisher, it sets the publisher's
+// default encoding, which a per-Put WithEncoding overrides.
+func WithEncoding(encoding Encoding) Option {
+	return func(o *options) {
+		o.encoding = &encoding
+	}
+}
+
+// WithPriority sets the message priority (applies to Session.Publisher).
+func WithPriority(priority Priority) Option {
+	return func(o *options) {
+		o.priority = &priority
+	}
+}
+
+// WithCongestionControl selects whether messages are dropped or the
+// publisher blocks when the network is congested (applies to Session.Publisher).
+func WithCongestionControl(cc CongestionControl) Option {
+	return func(o *options) {
+		o.congestionControl = &cc
+	}
+}
+
+// WithExpress sends messages immediately instead of batching them,
+// trading throughput for latency (applies to Session.Publisher).
+func WithExpress(express bool) Option {
+	return func(o *options) {
+		o.express = express
+	}
+}
+
+// WithReliability sets the transport reliability (applies to Session.Publisher).
+// The CGO backend requires zenoh-c built with the unstable API.
+func WithReliability(reliability Reliability) Option {
+	return func(o *options) {
+		o.reliability = &reliability
+	}
+}
+
+// WithHistory delivers a PUT sample for every liveliness token that is
+// already alive when the subscriber is declared (applies to SubscribeLiveliness).
+func WithHistory() Option {
+	return func(o *options) {
+		o.history = true
+	}
+}
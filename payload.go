@@ -0,0 +1,148 @@
+package zenoh
+
+import (
+	"io"
+	"iter"
+	"sync"
+)
+
+// Payload is a borrowed view of a received payload, passed to a
+// BorrowedHandler instead of a copy in Sample.Payload. The payload
+// may span several fragments of Zenoh's receive buffers, read in place
+// with Slices, Read or WriteTo.
+//
+// A Payload and the slices it yields are only valid until the handler
+// returns, and must not be retained; use Bytes or AppendTo to keep the
+// data.
+type Payload struct {
+	frags [][]byte
+	n     int
+
+	// off is the position of Read.
+	off int
+}
+
+// payloadPool recycles Payload views, so borrowing does not allocate.
+var payloadPool = sync.Pool{New: func() any { return new(Payload) }}
+
+// borrowPayload returns an empty Payload from the pool.
+func borrowPayload() *Payload {
+	return payloadPool.Get().(*Payload)
+}
+
+// add appends a fragment to the payload.
+func (p *Payload) add(frag []byte) {
+	if len(frag) > 0 {
+		p.frags = append(p.frags, frag)
+		p.n += len(frag)
+	}
+}
+
+// release forgets the fragments, which the backend is about to free,
+// and returns p to the pool.
+func (p *Payload) release() {
+	clear(p.frags)
+	p.frags = p.frags[:0]
+	p.n, p.off = 0, 0
+	payloadPool.Put(p)
+}
+
+// Len returns the payload size in bytes.
+func (p *Payload) Len() int {
+	return p.n
+}
+
+// Slices returns an iterator over the fragments of the payload, in
+// order. Most payloads have a single fragment.
+func (p *Payload) Slices() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		for _, frag := range p.frags {
+			if !yield(frag) {
+				return
+			}
+		}
+	}
+}
+
+// AppendTo appends the payload to dst and returns the extended slice.
+func (p *Payload) AppendTo(dst []byte) []byte {
+	for _, frag := range p.frags {
+		dst = append(dst, frag...)
+	}
+	return dst
+}
+
+// Bytes returns a copy of the payload, or nil if it is empty.
+func (p *Payload) Bytes() []byte {
+	if p.n == 0 {
+		return nil
+	}
+	return p.AppendTo(make([]byte, 0, p.n))
+}
+
+// Read implements io.Reader, reading the payload from the start.
+func (p *Payload) Read(b []byte) (int, error) {
+	if p.off >= p.n {
+		return 0, io.EOF
+	}
+
+	n, pos := 0, 0
+	for _, frag := range p.frags {
+		if p.off < pos+len(frag) {
+			c := copy(b[n:], frag[p.off-pos:])
+			n += c
+			p.off += c
+			if n == len(b) {
+				break
+			}
+		}
+		pos += len(frag)
+	}
+	return n, nil
+}
+
+// WriteTo implements io.WriterTo, writing the unread part of the
+// payload to w.
+func (p *Payload) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	pos := 0
+	for _, frag := range p.frags {
+		if p.off < pos+len(frag) {
+			rest := frag[p.off-pos:]
+			n, err := w.Write(rest)
+			total += int64(n)
+			p.off += n
+			if err == nil && n < len(rest) {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				return total, err
+			}
+		}
+		pos += len(frag)
+	}
+	return total, nil
+}
+
+// BorrowedHandler is called with samples whose payload is borrowed
+// rather than copied: Sample.Payload is nil, and payload is valid only
+// until the handler returns. It saves an allocation and a copy per
+// sample, for high-rate subscribers that decode payloads in place.
+type BorrowedHandler func(sample Sample, payload *Payload)
+
+// payloadBuffers recycles the buffers of pooled handlers.
+var payloadBuffers = sync.Pool{New: func() any { return new([]byte) }}
+
+// pooledHandler calls handler with the payload copied to a buffer from
+// payloadBuffers, which is recycled when handler returns.
+func pooledHandler(handler Handler) BorrowedHandler {
+	return func(sample Sample, payload *Payload) {
+		buf := payloadBuffers.Get().(*[]byte)
+		*buf = payload.AppendTo((*buf)[:0])
+		if len(*buf) > 0 {
+			sample.Payload = *buf
+		}
+		handler(sample)
+		payloadBuffers.Put(buf)
+	}
+}
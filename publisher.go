@@ -6,6 +6,10 @@ package zenoh
 // for multiple Put operations. They are automatically closed when
 // the session is closed.
 //
+// Publishers declared on the same key expression with the same options
+// share one declaration in the session; closing one leaves the others
+// usable, and the declaration is undeclared when the last one is closed.
+//
 // Example:
 //
 //	pub, err := session.Publisher("reachy_mini/command")
@@ -16,20 +20,37 @@ package zenoh
 //	// Publish multiple times
 //	pub.Put([]byte(`{"action": "look_left"}`))
 //	pub.Put([]byte(`{"action": "look_right"}`))
+//
+//	// Send metadata alongside the payload
+//	pub.Put(cmd, zenoh.WithAttachmentMap(map[string]string{"request_id": "42"}))
 type Publisher interface {
 	// Put publishes data to the key expression.
 	// The data is sent asynchronously.
-	Put(data []byte) error
+	// Use WithAttachment or WithAttachmentMap to send metadata along,
+	// and WithEncoding to override the publisher's default encoding.
+	Put(data []byte, opts ...Option) error
 
 	// Delete publishes a deletion to the key expression.
 	// This notifies subscribers that the resource was deleted.
-	Delete() error
+	// Attachments are not supported on DELETE by zenoh-c 1.0 and are
+	// rejected with errors.ErrUnsupported.
+	Delete(opts ...Option) error
 
 	// Close releases the publisher resources.
 	// After Close, Put and Delete will return errors.
+	// Close waits for Put and Delete calls in progress; closing a
+	// publisher more than once is a no-op.
 	Close() error
 }
 
+// publisherKey identifies publishers that can share one declaration:
+// same key expression and same declaration-time options.
+type publisherKey struct {
+	keyExpr  KeyExpr
+	encoding Encoding
+	qos      publisherQoS
+}
+
 
 
 
@@ -9,56 +9,78 @@ import "C"
 
 import (
 	"fmt"
-	"unsafe"
+	"runtime"
 )
 
-// cgoPublisher wraps a native Zenoh publisher.
+// cgoPublisherDecl is a native Zenoh publisher, shared by the
+// cgoPublisher handles declared with the same publisherKey.
+//
+// It is dropped by whoever removes it from the session: the last
+// handle's Close, or session.Close(), after the puts in progress.
+type cgoPublisherDecl struct {
+	pub   C.z_owned_publisher_t
+	refs  int
+	calls inFlight
+
+	// declared is the declared key expression it was declared with,
+	// if any, referenced until it is undeclared.
+	declared *cgoKeyExpr
+}
+
+// cgoPublisher is a handle on a shared native publisher.
 type cgoPublisher struct {
 	session *cgoSession
-	keyExpr KeyExpr
-	pub     C.z_owned_publisher_t
-	closed  bool
+	key     publisherKey
+	decl    *cgoPublisherDecl
+
+	// calls tracks Put and Delete calls through this handle.
+	calls inFlight
 }
 
-func (p *cgoPublisher) Put(data []byte) error {
-	if p.closed {
-		return ErrSessionClosed
+// enter registers a call on both the handle and the declaration.
+func (p *cgoPublisher) enter() bool {
+	if !p.calls.enter() {
+		return false
 	}
-
-	if len(data) == 0 {
-		return p.putEmpty()
+	if !p.decl.calls.enter() {
+		p.calls.exit()
+		return false
 	}
+	return true
+}
 
-	// Create bytes from buffer
-	var payload C.z_owned_bytes_t
-	C.z_bytes_copy_from_buf(
-		&payload,
-		(*C.uint8_t)(unsafe.Pointer(&data[0])),
-		C.size_t(len(data)),
-	)
-
-	// Put
-	result := C.z_publisher_put(
-		C.z_publisher_loan(&p.pub),
-		C.z_bytes_move(&payload),
-		nil,
-	)
+func (p *cgoPublisher) exit() {
+	p.decl.calls.exit()
+	p.calls.exit()
+}
 
-	if result < 0 {
-		return fmt.Errorf("%w: error code %d", ErrPublishFailed, result)
+func (p *cgoPublisher) Put(data []byte, opts ...Option) error {
+	if !p.enter() {
+		return ErrSessionClosed
 	}
+	defer p.exit()
+	o := collectOptions(opts)
 
-	return nil
-}
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	var putOpts C.z_publisher_put_options_t
+	C.z_publisher_put_options_default(&putOpts)
+	if o.encoding != nil {
+		putOpts.encoding = pinnedEncoding(&pinner, *o.encoding)
+	}
+	if o.attachment != nil {
+		putOpts.attachment = pinnedBytes(&pinner, o.attachment)
+	}
 
-func (p *cgoPublisher) putEmpty() error {
-	var payload C.z_owned_bytes_t
-	C.z_bytes_empty(&payload)
+	// Create bytes from buffer (empty data is sent as empty bytes)
+	payload := bytesToC(data)
 
+	// Put
 	result := C.z_publisher_put(
-		C.z_publisher_loan(&p.pub),
+		C.z_publisher_loan(&p.decl.pub),
 		C.z_bytes_move(&payload),
-		nil,
+		&putOpts,
 	)
 
 	if result < 0 {
@@ -68,13 +90,18 @@ func (p *cgoPublisher) putEmpty() error {
 	return nil
 }
 
-func (p *cgoPublisher) Delete() error {
-	if p.closed {
+func (p *cgoPublisher) Delete(opts ...Option) error {
+	if !p.enter() {
 		return ErrSessionClosed
 	}
+	defer p.exit()
+
+	if o := collectOptions(opts); o.attachment != nil {
+		return errDeleteAttachment
+	}
 
 	result := C.z_publisher_delete(
-		C.z_publisher_loan(&p.pub),
+		C.z_publisher_loan(&p.decl.pub),
 		nil,
 	)
 	if result < 0 {
@@ -84,10 +111,10 @@ func (p *cgoPublisher) Delete() error {
 }
 
 func (p *cgoPublisher) Close() error {
-	// Publisher cleanup is handled by session.Close()
-	// Mark as closed to prevent further operations
-	p.closed = true
-	return nil
+	if !p.calls.close() {
+		return nil
+	}
+	return p.session.releasePublisher(p.key, p.decl)
 }
 
 
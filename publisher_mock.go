@@ -2,31 +2,74 @@
 
 package zenoh
 
+import "sync/atomic"
+
 // mockPublisher implements Publisher for testing.
 type mockPublisher struct {
-	session *mockSession
-	keyExpr KeyExpr
-	closed  bool
+	session  *mockSession
+	key      publisherKey
+	keyExpr  KeyExpr
+	encoding Encoding
+	qos      QoS
+
+	// entityID and sn fill the SourceInfo of samples; sn may be shared.
+	entityID uint32
+	sn       *atomic.Uint32
+
+	// calls tracks Put and Delete calls, so Close waits for them.
+	calls inFlight
 }
 
-func (p *mockPublisher) Put(data []byte) error {
-	if p.closed {
+func (p *mockPublisher) Put(data []byte, opts ...Option) error {
+	if !p.calls.enter() {
 		return ErrSessionClosed
 	}
-	p.session.publish(p.keyExpr, data, SampleKindPut)
-	return nil
+	defer p.calls.exit()
+
+	o := collectOptions(opts)
+	return p.session.publish(Sample{
+		KeyExpr:    p.keyExpr,
+		Payload:    data,
+		Kind:       SampleKindPut,
+		Encoding:   o.encodingOr(p.encoding),
+		QoS:        p.qos,
+		Attachment: o.attachment,
+		SourceInfo: p.sourceInfo(),
+	})
 }
 
-func (p *mockPublisher) Delete() error {
-	if p.closed {
+func (p *mockPublisher) Delete(opts ...Option) error {
+	if !p.calls.enter() {
 		return ErrSessionClosed
 	}
-	p.session.publish(p.keyExpr, nil, SampleKindDelete)
-	return nil
+	defer p.calls.exit()
+
+	o := collectOptions(opts)
+	if o.attachment != nil {
+		return errDeleteAttachment
+	}
+	return p.session.publish(Sample{
+		KeyExpr:    p.keyExpr,
+		Kind:       SampleKindDelete,
+		QoS:        p.qos,
+		SourceInfo: p.sourceInfo(),
+	})
+}
+
+// sourceInfo returns the SourceInfo of the next sample.
+func (p *mockPublisher) sourceInfo() *SourceInfo {
+	return &SourceInfo{
+		ID:       p.session.id,
+		EntityID: p.entityID,
+		SN:       p.sn.Add(1) - 1,
+	}
 }
 
 func (p *mockPublisher) Close() error {
-	p.closed = true
+	if !p.calls.close() {
+		return nil
+	}
+	p.session.releasePublisher(p.key)
 	return nil
 }
 
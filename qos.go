@@ -0,0 +1,157 @@
+package zenoh
+
+import "fmt"
+
+// Priority is the Zenoh message priority. Lower values are more urgent.
+type Priority int
+
+const (
+	// PriorityRealTime is for time-critical traffic such as motor commands.
+	PriorityRealTime Priority = 1
+
+	// PriorityInteractiveHigh is for high-priority interactive traffic.
+	PriorityInteractiveHigh Priority = 2
+
+	// PriorityInteractiveLow is for low-priority interactive traffic.
+	PriorityInteractiveLow Priority = 3
+
+	// PriorityDataHigh is for high-priority data.
+	PriorityDataHigh Priority = 4
+
+	// PriorityData is the default priority.
+	PriorityData Priority = 5
+
+	// PriorityDataLow is for low-priority data.
+	PriorityDataLow Priority = 6
+
+	// PriorityBackground is for background traffic such as logs.
+	PriorityBackground Priority = 7
+
+	// PriorityDefault is the priority used when none is set.
+	PriorityDefault = PriorityData
+)
+
+// String returns a string representation of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityRealTime:
+		return "REAL_TIME"
+	case PriorityInteractiveHigh:
+		return "INTERACTIVE_HIGH"
+	case PriorityInteractiveLow:
+		return "INTERACTIVE_LOW"
+	case PriorityDataHigh:
+		return "DATA_HIGH"
+	case PriorityData:
+		return "DATA"
+	case PriorityDataLow:
+		return "DATA_LOW"
+	case PriorityBackground:
+		return "BACKGROUND"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// CongestionControl selects what happens when the network is congested.
+type CongestionControl int
+
+const (
+	// CongestionControlBlock blocks the publisher until the message can be sent.
+	CongestionControlBlock CongestionControl = 0
+
+	// CongestionControlDrop drops the message.
+	CongestionControlDrop CongestionControl = 1
+
+	// CongestionControlDefault is the congestion control used when none is set.
+	CongestionControlDefault = CongestionControlDrop
+)
+
+// String returns a string representation of the congestion control.
+func (c CongestionControl) String() string {
+	switch c {
+	case CongestionControlBlock:
+		return "BLOCK"
+	case CongestionControlDrop:
+		return "DROP"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Reliability selects the reliability of the underlying transport.
+type Reliability int
+
+const (
+	// ReliabilityBestEffort may lose messages.
+	ReliabilityBestEffort Reliability = 0
+
+	// ReliabilityReliable retransmits lost messages.
+	ReliabilityReliable Reliability = 1
+
+	// ReliabilityDefault is the reliability used when none is set.
+	ReliabilityDefault = ReliabilityReliable
+)
+
+// String returns a string representation of the reliability.
+func (r Reliability) String() string {
+	switch r {
+	case ReliabilityBestEffort:
+		return "BEST_EFFORT"
+	case ReliabilityReliable:
+		return "RELIABLE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// QoS describes the quality of service a sample was published with.
+type QoS struct {
+	// Priority of the sample.
+	Priority Priority
+
+	// CongestionControl applied to the sample.
+	CongestionControl CongestionControl
+
+	// Express is true if the sample was sent without batching.
+	Express bool
+}
+
+// DefaultQoS returns the QoS used when no option is set.
+func DefaultQoS() QoS {
+	return QoS{
+		Priority:          PriorityDefault,
+		CongestionControl: CongestionControlDefault,
+	}
+}
+
+// publisherQoS holds the declaration-time QoS settings of a publisher.
+type publisherQoS struct {
+	QoS
+	reliability Reliability
+}
+
+// publisherQoS resolves the QoS options against their defaults.
+func (o options) publisherQoS() (publisherQoS, error) {
+	q := publisherQoS{QoS: DefaultQoS(), reliability: ReliabilityDefault}
+	if o.priority != nil {
+		if *o.priority < PriorityRealTime || *o.priority > PriorityBackground {
+			return q, fmt.Errorf("%w: invalid priority %d", ErrPublishFailed, *o.priority)
+		}
+		q.Priority = *o.priority
+	}
+	if o.congestionControl != nil {
+		if *o.congestionControl != CongestionControlBlock && *o.congestionControl != CongestionControlDrop {
+			return q, fmt.Errorf("%w: invalid congestion control %d", ErrPublishFailed, *o.congestionControl)
+		}
+		q.CongestionControl = *o.congestionControl
+	}
+	if o.reliability != nil {
+		if *o.reliability != ReliabilityBestEffort && *o.reliability != ReliabilityReliable {
+			return q, fmt.Errorf("%w: invalid reliability %d", ErrPublishFailed, *o.reliability)
+		}
+		q.reliability = *o.reliability
+	}
+	q.Express = o.express
+	return q, nil
+}
@@ -0,0 +1,202 @@
+package zenoh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// Query is a request received by a Queryable.
+//
+// A Query is only valid for the duration of the QueryHandler call.
+// Replies sent after the handler returns fail with ErrQueryFailed.
+type Query struct {
+	// KeyExpr is the key expression of the query.
+	KeyExpr KeyExpr
+
+	// Parameters of the selector (the part after '?'), if any.
+	Parameters string
+
+	// Payload sent with the query, or nil if none.
+	Payload []byte
+
+	// Encoding of the payload.
+	Encoding Encoding
+
+	// Attachment sent with the query, or nil if none.
+	Attachment []byte
+
+	replier queryReplier
+}
+
+// Selector returns the full selector, "keyexpr?parameters".
+func (q Query) Selector() string {
+	if q.Parameters == "" {
+		return string(q.KeyExpr)
+	}
+	return string(q.KeyExpr) + "?" + q.Parameters
+}
+
+// Reply sends a sample in response to the query.
+// keyExpr must intersect the query's key expression.
+// Use WithEncoding and WithAttachment to describe the reply.
+func (q Query) Reply(keyExpr KeyExpr, data []byte, opts ...Option) error {
+	if q.replier == nil {
+		return fmt.Errorf("%w: query has no replier", ErrQueryFailed)
+	}
+	return q.replier.reply(keyExpr, data, SampleKindPut, collectOptions(opts))
+}
+
+// ReplyDelete sends a DELETE sample in response to the query.
+// keyExpr must intersect the query's key expression.
+// Use WithAttachment to send metadata along.
+func (q Query) ReplyDelete(keyExpr KeyExpr, opts ...Option) error {
+	if q.replier == nil {
+		return fmt.Errorf("%w: query has no replier", ErrQueryFailed)
+	}
+	return q.replier.reply(keyExpr, nil, SampleKindDelete, collectOptions(opts))
+}
+
+// ReplyErr sends an error reply. The querier receives it as a *ReplyError.
+func (q Query) ReplyErr(data []byte) error {
+	if q.replier == nil {
+		return fmt.Errorf("%w: query has no replier", ErrQueryFailed)
+	}
+	return q.replier.replyErr(data)
+}
+
+// QueryHandler is called when a query is received.
+// Replies must be sent before the handler returns.
+type QueryHandler func(Query)
+
+// queryReplier is implemented by each backend to send replies.
+type queryReplier interface {
+	reply(keyExpr KeyExpr, data []byte, kind SampleKind, o options) error
+	replyErr(data []byte) error
+}
+
+// Reply is a successful reply to a query, as streamed by Session.GetStream.
+type Reply struct {
+	// Sample holds the key expression, payload and metadata of the reply.
+	Sample
+}
+
+// replyCollector gathers the replies of a single query.
+// Replies may arrive from several goroutines; done is closed when the query ends.
+type replyCollector struct {
+	mu      sync.Mutex
+	replies []queryReply
+	ready   chan struct{}
+	done    chan struct{}
+}
+
+// queryReply is an OK sample or an error reply, in arrival order.
+type queryReply struct {
+	sample Sample
+	err    error
+}
+
+func newReplyCollector() *replyCollector {
+	return &replyCollector{
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+func (c *replyCollector) addSample(s Sample) {
+	c.add(queryReply{sample: s})
+}
+
+func (c *replyCollector) addError(err error) {
+	c.add(queryReply{err: err})
+}
+
+func (c *replyCollector) add(r queryReply) {
+	c.mu.Lock()
+	c.replies = append(c.replies, r)
+	c.mu.Unlock()
+
+	// Wake a waiting stream without blocking the reply thread
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (c *replyCollector) finish() {
+	close(c.done)
+}
+
+// wait blocks until the query ends and returns its result.
+func (c *replyCollector) wait(ctx context.Context) ([]Sample, error) {
+	select {
+	case <-c.done:
+		return c.result()
+	case <-ctx.Done():
+		return nil, contextError(ctx.Err())
+	}
+}
+
+// result returns the OK replies and any reply errors joined together.
+func (c *replyCollector) result() ([]Sample, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var samples []Sample
+	var errs []error
+	for _, r := range c.replies {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		} else {
+			samples = append(samples, r.sample)
+		}
+	}
+	return samples, errors.Join(errs...)
+}
+
+// stream yields replies as they arrive, releasing each batch once
+// yielded. Error replies are yielded without ending the stream;
+// ctx expiry is yielded last.
+func (c *replyCollector) stream(ctx context.Context) iter.Seq2[Reply, error] {
+	return func(yield func(Reply, error) bool) {
+		for {
+			c.mu.Lock()
+			pending := c.replies
+			c.replies = nil
+			c.mu.Unlock()
+
+			for _, r := range pending {
+				if !yield(Reply{Sample: r.sample}, r.err) {
+					return
+				}
+			}
+			if len(pending) > 0 {
+				continue
+			}
+
+			select {
+			case <-c.ready:
+			case <-c.done:
+				// Yield replies added just before finish, then stop
+				c.mu.Lock()
+				drained := len(c.replies) == 0
+				c.mu.Unlock()
+				if drained {
+					return
+				}
+			case <-ctx.Done():
+				yield(Reply{}, contextError(ctx.Err()))
+				return
+			}
+		}
+	}
+}
+
+// errorStream yields err alone, for queries that fail to start.
+func errorStream(err error) iter.Seq2[Reply, error] {
+	return func(yield func(Reply, error) bool) {
+		yield(Reply{}, err)
+	}
+}
@@ -0,0 +1,24 @@
+package zenoh
+
+// Queryable represents an active queryable.
+//
+// Queryables are created via Session.DeclareQueryable() and answer
+// Get requests from this and other sessions through the provided
+// QueryHandler. They are automatically closed when the session is closed.
+//
+// Example:
+//
+//	q, err := session.DeclareQueryable("reachy_mini/state", func(q zenoh.Query) {
+//	    q.Reply("reachy_mini/state", []byte(`{"mode": "idle"}`))
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer q.Close()
+type Queryable interface {
+	// Close undeclares the queryable and releases resources.
+	// After Close, no more queries will be delivered to the handler.
+	// Close waits for handler calls in progress to return, so it must
+	// not be called from the handler itself.
+	Close() error
+}
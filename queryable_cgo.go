@@ -0,0 +1,139 @@
+//go:build cgo
+
+package zenoh
+
+/*
+#include <zenoh.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+// cgoQueryable wraps a native Zenoh queryable.
+// The cgo handle is deleted when zenoh-c drops the closure.
+type cgoQueryable struct {
+	session   *cgoSession
+	keyExpr   KeyExpr
+	handler   QueryHandler
+	queryable C.z_owned_queryable_t
+	handle    cgo.Handle
+	closed    bool
+
+	// callbacks tracks the handler calls in progress.
+	callbacks inFlight
+}
+
+func (q *cgoQueryable) Close() error {
+	q.session.mu.Lock()
+	if q.closed || q.session.closed {
+		// Already released, possibly by session.Close()
+		q.closed = true
+		q.session.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+
+	// Remove from session so session.Close() does not drop it again
+	for i, other := range q.session.queryables {
+		if other == q {
+			q.session.queryables = append(q.session.queryables[:i], q.session.queryables[i+1:]...)
+			break
+		}
+	}
+
+	// Drop without the lock, like liveliness tokens; session.Close()
+	// waits for the call
+	q.session.calls.enter()
+	q.session.mu.Unlock()
+	defer q.session.calls.exit()
+
+	// Stop new queries and wait for running handlers, then drop
+	q.callbacks.close()
+	C.z_queryable_drop(C.z_queryable_move(&q.queryable))
+
+	return nil
+}
+
+// cgoQueryReplier answers a loaned zenoh-c query.
+type cgoQueryReplier struct {
+	query *C.z_loaned_query_t
+
+	mu   sync.Mutex
+	done bool
+}
+
+func (r *cgoQueryReplier) reply(keyExpr KeyExpr, data []byte, kind SampleKind, o options) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return fmt.Errorf("%w: query already finalized", ErrQueryFailed)
+	}
+
+	// Create key expression
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	var ke C.z_view_keyexpr_t
+	if C.z_view_keyexpr_from_str(&ke, cKeyExpr) < 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidKeyExpr, keyExpr)
+	}
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	var result C.z_result_t
+	if kind == SampleKindDelete {
+		var delOpts C.z_query_reply_del_options_t
+		C.z_query_reply_del_options_default(&delOpts)
+		if o.attachment != nil {
+			delOpts.attachment = pinnedBytes(&pinner, o.attachment)
+		}
+		result = C.z_query_reply_del(r.query, C.z_view_keyexpr_loan(&ke), &delOpts)
+	} else {
+		var replyOpts C.z_query_reply_options_t
+		C.z_query_reply_options_default(&replyOpts)
+		if o.encoding != nil {
+			replyOpts.encoding = pinnedEncoding(&pinner, *o.encoding)
+		}
+		if o.attachment != nil {
+			replyOpts.attachment = pinnedBytes(&pinner, o.attachment)
+		}
+		payload := bytesToC(data)
+		result = C.z_query_reply(r.query, C.z_view_keyexpr_loan(&ke), C.z_bytes_move(&payload), &replyOpts)
+	}
+	if result < 0 {
+		return fmt.Errorf("%w: reply error code %d", ErrQueryFailed, result)
+	}
+	return nil
+}
+
+func (r *cgoQueryReplier) replyErr(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return fmt.Errorf("%w: query already finalized", ErrQueryFailed)
+	}
+
+	payload := bytesToC(data)
+	result := C.z_query_reply_err(r.query, C.z_bytes_move(&payload), nil)
+	if result < 0 {
+		return fmt.Errorf("%w: reply error code %d", ErrQueryFailed, result)
+	}
+	return nil
+}
+
+// finish marks the query as finalized once the handler has returned.
+func (r *cgoQueryReplier) finish() {
+	r.mu.Lock()
+	r.done = true
+	r.mu.Unlock()
+}
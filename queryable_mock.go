@@ -0,0 +1,86 @@
+//go:build !cgo
+
+package zenoh
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// mockQueryable implements Queryable for testing.
+type mockQueryable struct {
+	session *mockSession
+	keyExpr KeyExpr
+	handler QueryHandler
+
+	// calls tracks the handler calls in progress, entered by query.
+	calls inFlight
+}
+
+func (q *mockQueryable) Close() error {
+	// Remove queryable from session
+	q.session.mu.Lock()
+	for i, other := range q.session.queryables {
+		if other == q {
+			q.session.queryables = append(q.session.queryables[:i], q.session.queryables[i+1:]...)
+			break
+		}
+	}
+	q.session.mu.Unlock()
+
+	// Wait for queries already routed, like the cgo backend
+	q.calls.close()
+	return nil
+}
+
+// mockQueryReplier delivers replies straight into the querier's collector.
+type mockQueryReplier struct {
+	keyExpr   KeyExpr
+	collector *replyCollector
+
+	mu   sync.Mutex
+	done bool
+}
+
+func (r *mockQueryReplier) reply(keyExpr KeyExpr, data []byte, kind SampleKind, o options) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return fmt.Errorf("%w: query already finalized", ErrQueryFailed)
+	}
+	if !Intersects(r.keyExpr, keyExpr) {
+		return fmt.Errorf("%w: reply key %s does not match query %s", ErrQueryFailed, keyExpr, r.keyExpr)
+	}
+
+	r.collector.addSample(Sample{
+		KeyExpr:    keyExpr,
+		Payload:    data,
+		ReceivedAt: time.Now(),
+		Kind:       kind,
+		Encoding:   o.encodingOr(EncodingZenohBytes),
+		QoS:        DefaultQoS(),
+		Attachment: o.attachment,
+	})
+	return nil
+}
+
+func (r *mockQueryReplier) replyErr(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return fmt.Errorf("%w: query already finalized", ErrQueryFailed)
+	}
+
+	r.collector.addError(&ReplyError{Payload: data})
+	return nil
+}
+
+// finish marks the query as finalized once the handler has returned.
+func (r *mockQueryReplier) finish() {
+	r.mu.Lock()
+	r.done = true
+	r.mu.Unlock()
+}
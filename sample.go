@@ -8,11 +8,16 @@ import "time"
 // They support wildcards:
 //   - "*" matches any single chunk (between slashes)
 //   - "**" matches any sequence of chunks
+//   - "$*" within a chunk matches any characters of that chunk
 //
 // Examples:
 //   - "reachy_mini/command" - exact match
 //   - "reachy_mini/*" - matches reachy_mini/command, reachy_mini/status, etc.
 //   - "reachy_mini/**" - matches all under reachy_mini/
+//   - "reachy_mini/joint_$*" - matches reachy_mini/joint_1, reachy_mini/joint_head, etc.
+//
+// Publisher and Subscribe reject key expressions that fail Validate;
+// use Canonize to fix ones that are only non-canonical.
 type KeyExpr string
 
 // String returns the key expression as a string.
@@ -28,11 +33,42 @@ type Sample struct {
 	// Payload is the raw bytes of the sample.
 	Payload []byte
 
-	// Timestamp when the sample was received locally.
-	Timestamp time.Time
+	// Timestamp is the HLC timestamp Zenoh stamped the sample with,
+	// or the zero Timestamp if it has none.
+	Timestamp Timestamp
+
+	// ReceivedAt is when the sample was received locally.
+	ReceivedAt time.Time
 
 	// Kind indicates PUT or DELETE.
 	Kind SampleKind
+
+	// Encoding of the payload, e.g. EncodingApplicationJSON.
+	// Samples published without an encoding carry EncodingZenohBytes.
+	Encoding Encoding
+
+	// QoS the sample was published with.
+	QoS QoS
+
+	// Attachment is user metadata sent alongside the payload, or nil if none.
+	// Use DecodeAttachment if it was sent with WithAttachmentMap.
+	Attachment []byte
+
+	// SourceInfo identifies the entity that published the sample, or is
+	// nil if the publisher did not send it.
+	SourceInfo *SourceInfo
+}
+
+// SourceInfo identifies the publication a sample comes from.
+type SourceInfo struct {
+	// ID is the ID of the publishing session.
+	ID ZenohID
+
+	// EntityID identifies the publisher within its session.
+	EntityID uint32
+
+	// SN is the sequence number of the sample from that publisher.
+	SN uint32
 }
 
 // String returns the payload as a string.
@@ -3,6 +3,7 @@ package zenoh
 import (
 	"context"
 	"fmt"
+	"iter"
 )
 
 // Session represents a Zenoh session.
@@ -10,16 +11,79 @@ import (
 type Session interface {
 	// Publisher declares a publisher for the given key expression.
 	// Publishers can be reused for multiple Put operations.
-	Publisher(keyExpr KeyExpr) (Publisher, error)
+	// Use WithEncoding to set the default encoding of published samples,
+	// and WithPriority, WithCongestionControl, WithExpress and
+	// WithReliability to set its QoS.
+	Publisher(keyExpr KeyExpr, opts ...Option) (Publisher, error)
+
+	// Put publishes data to keyExpr without declaring a publisher,
+	// for one-off writes. It takes the options of Publisher and
+	// Publisher.Put; prefer a Publisher for repeated writes.
+	Put(keyExpr KeyExpr, data []byte, opts ...Option) error
+
+	// Delete publishes a deletion to keyExpr without declaring a
+	// publisher. It takes the QoS options of Publisher; attachments
+	// are rejected like in Publisher.Delete.
+	Delete(keyExpr KeyExpr, opts ...Option) error
 
 	// Subscribe creates a subscriber for the given key expression.
 	// The handler is called for each received sample.
 	// Supports wildcards: "topic/*" or "topic/**"
 	Subscribe(keyExpr KeyExpr, handler Handler) (Subscriber, error)
 
+	// SubscribeChan creates a subscriber that queues received samples
+	// for ChannelSubscriber.Recv, bounded as selected by handler:
+	// FifoChannel or RingChannel.
+	SubscribeChan(keyExpr KeyExpr, handler ChannelHandler) (ChannelSubscriber, error)
+
+	// SubscribeBorrowed creates a subscriber whose handler reads each
+	// payload in place through a Payload, without copying it.
+	SubscribeBorrowed(keyExpr KeyExpr, handler BorrowedHandler) (Subscriber, error)
+
+	// SubscribePooled creates a subscriber whose Sample.Payload is a
+	// buffer from a shared pool, recycled when the handler returns;
+	// the handler must copy the payload to keep it.
+	SubscribePooled(keyExpr KeyExpr, handler Handler) (Subscriber, error)
+
 	// Get performs a query and returns matching samples.
 	// This is a blocking call that waits for replies.
-	Get(ctx context.Context, keyExpr KeyExpr) ([]Sample, error)
+	// The ctx deadline, if any, is used as the query timeout.
+	// Error replies are returned alongside the OK samples as *ReplyError
+	// values joined into the returned error.
+	// Use WithParameters, WithPayload, WithEncoding and WithAttachment
+	// to pass data to queryables.
+	Get(ctx context.Context, keyExpr KeyExpr, opts ...Option) ([]Sample, error)
+
+	// GetStream performs a query like Get, yielding replies as they
+	// arrive instead of waiting for the query to end. Error replies are
+	// yielded as *ReplyError and the stream goes on; any other error,
+	// such as ErrTimeout when ctx expires, is yielded last.
+	GetStream(ctx context.Context, keyExpr KeyExpr, opts ...Option) iter.Seq2[Reply, error]
+
+	// DeclareKeyExpr declares keyExpr on the session, so that it is sent
+	// as a short ID instead of the full key, and returns a handle to
+	// publish, subscribe and query on it.
+	DeclareKeyExpr(keyExpr KeyExpr) (DeclaredKeyExpr, error)
+
+	// DeclareQueryable declares a queryable for the given key expression.
+	// The handler is called for each query whose key expression
+	// intersects keyExpr, and answers it with Query.Reply.
+	DeclareQueryable(keyExpr KeyExpr, handler QueryHandler) (Queryable, error)
+
+	// DeclareLivelinessToken declares a liveliness token for keyExpr.
+	// The token stays alive until it is closed or the session goes away.
+	DeclareLivelinessToken(keyExpr KeyExpr) (LivelinessToken, error)
+
+	// SubscribeLiveliness subscribes to liveliness changes on keyExpr.
+	// The handler receives a PUT sample when a matching token appears
+	// and a DELETE sample when it disappears.
+	// Use WithHistory to also receive the tokens that are already alive.
+	SubscribeLiveliness(keyExpr KeyExpr, handler Handler, opts ...Option) (Subscriber, error)
+
+	// GetLiveliness returns a PUT sample for every liveliness token
+	// currently alive on keyExpr.
+	// The ctx deadline, if any, is used as the query timeout.
+	GetLiveliness(ctx context.Context, keyExpr KeyExpr) ([]Sample, error)
 
 	// Close closes the session and all associated resources.
 	// After Close, all operations on the session will return ErrSessionClosed.
@@ -27,17 +91,26 @@ type Session interface {
 
 	// Info returns session information for debugging.
 	Info() SessionInfo
+
+	// RouterIDs returns the IDs of the routers this session is
+	// currently connected to.
+	RouterIDs() ([]ZenohID, error)
+
+	// PeerIDs returns the IDs of the peers this session is
+	// currently connected to.
+	PeerIDs() ([]ZenohID, error)
 }
 
 // SessionInfo contains session metadata.
 type SessionInfo struct {
-	// ID is the unique session identifier (if available).
-	ID string
+	// ID is the Zenoh ID of this session (zero once closed).
+	ID ZenohID
 
-	// Mode is "peer" or "client".
+	// Mode is "peer", "client" or "router".
 	Mode string
 
-	// Endpoints this session is connected to.
+	// Endpoints this session was configured to connect to.
+	// See RouterIDs and PeerIDs for the current connections.
 	Endpoints []string
 
 	// UsingCGO indicates if native bindings are used.
@@ -10,31 +10,136 @@ package zenoh
 #include <stdlib.h>
 #include <string.h>
 
-// Forward declaration for Go callback (signature must match exactly)
+// Forward declarations for Go callbacks (signatures must match exactly)
 extern void goSampleCallback(struct z_loaned_sample_t*, void*);
+extern void goSampleDropCallback(void*);
+extern void goReplyCallback(struct z_loaned_reply_t*, void*);
+extern void goReplyDropCallback(void*);
+extern void goQueryCallback(struct z_loaned_query_t*, void*);
+extern void goQueryDropCallback(void*);
+extern void goZIDCallback(z_id_t*, void*);
 
 // Callback wrapper that C can call
 static void sample_callback_wrapper(struct z_loaned_sample_t* sample, void* context) {
     goSampleCallback(sample, context);
 }
 
-// Helper to create closure with our wrapper
-static z_owned_closure_sample_t make_sample_closure(void* context) {
+static void sample_drop_wrapper(void* context) {
+    goSampleDropCallback(context);
+}
+
+// Helper to create closure with our wrapper.
+// The context is a cgo.Handle, passed as an integer so Go never
+// converts it to a pointer. The drop callback releases the handle once
+// zenoh-c is done with the closure.
+static z_owned_closure_sample_t make_sample_closure(uintptr_t context) {
     z_owned_closure_sample_t closure;
-    z_closure_sample(&closure, sample_callback_wrapper, NULL, context);
+    z_closure_sample(&closure, sample_callback_wrapper, sample_drop_wrapper, (void*)context);
+    return closure;
+}
+
+static void reply_callback_wrapper(struct z_loaned_reply_t* reply, void* context) {
+    goReplyCallback(reply, context);
+}
+
+static void reply_drop_wrapper(void* context) {
+    goReplyDropCallback(context);
+}
+
+// Helper to create a reply closure for z_get.
+// The drop callback fires once all replies have been received
+// (or the query timed out), which is how Go learns the query is done.
+static z_owned_closure_reply_t make_reply_closure(uintptr_t context) {
+    z_owned_closure_reply_t closure;
+    z_closure_reply(&closure, reply_callback_wrapper, reply_drop_wrapper, (void*)context);
+    return closure;
+}
+
+static void query_callback_wrapper(struct z_loaned_query_t* query, void* context) {
+    goQueryCallback(query, context);
+}
+
+static void query_drop_wrapper(void* context) {
+    goQueryDropCallback(context);
+}
+
+// Helper to create a query closure for z_declare_queryable.
+// Like sample closures, the drop callback releases the handle.
+static z_owned_closure_query_t make_query_closure(uintptr_t context) {
+    z_owned_closure_query_t closure;
+    z_closure_query(&closure, query_callback_wrapper, query_drop_wrapper, (void*)context);
+    return closure;
+}
+
+static void zid_callback_wrapper(const z_id_t* id, void* context) {
+    goZIDCallback((z_id_t*)id, context);
+}
+
+// Helper to create a zid closure for z_info_routers_zid and
+// z_info_peers_zid, which call it synchronously.
+static z_owned_closure_zid_t make_zid_closure(uintptr_t context) {
+    z_owned_closure_zid_t closure;
+    z_closure_zid(&closure, zid_callback_wrapper, NULL, (void*)context);
     return closure;
 }
 
+// Helper to set publisher reliability, which zenoh-c 1.0 only exposes
+// with the unstable API. Returns 0 on success, -1 if unsupported.
+static int publisher_options_set_reliability(z_publisher_options_t* opts, int reliability) {
+#if defined(Z_FEATURE_UNSTABLE_API)
+    opts->reliability = (z_reliability_t)reliability;
+    return 0;
+#else
+    return -1;
+#endif
+}
+
+// Same for z_put and z_delete options.
+static int put_options_set_reliability(z_put_options_t* opts, int reliability) {
+#if defined(Z_FEATURE_UNSTABLE_API)
+    opts->reliability = (z_reliability_t)reliability;
+    return 0;
+#else
+    return -1;
+#endif
+}
+
+static int delete_options_set_reliability(z_delete_options_t* opts, int reliability) {
+#if defined(Z_FEATURE_UNSTABLE_API)
+    opts->reliability = (z_reliability_t)reliability;
+    return 0;
+#else
+    return -1;
+#endif
+}
+
+// Helper to read the source info of a sample, which zenoh-c 1.0 only
+// exposes with the unstable API. Returns false if unsupported; a sample
+// published without source info has a zero ID.
+static bool sample_source_info(const z_loaned_sample_t* sample, z_id_t* zid, uint32_t* eid, uint32_t* sn) {
+#if defined(Z_FEATURE_UNSTABLE_API)
+    const z_loaned_source_info_t* info = z_sample_source_info(sample);
+    if (info == NULL) {
+        return false;
+    }
+    z_entity_global_id_t id = z_source_info_id(info);
+    *zid = z_entity_global_id_zid(&id);
+    *eid = z_entity_global_id_eid(&id);
+    *sn = z_source_info_sn(info);
+    return true;
+#else
+    return false;
+#endif
+}
+
 // Helper to create config from JSON5 string
 // Returns 0 on success, negative on error
 static int config_from_json5(z_owned_config_t* config, const char* json5) {
     // zenoh-c 1.x uses zc_config_from_str
-    z_result_t result = zc_config_from_str(config, json5);
-    return (int)result;
+    return (int)zc_config_from_str(config, json5);
 }
 
 // Helper to insert JSON5 into existing config
-// This is an alternative approach if z_config_from_str doesn't work
 static int config_insert_json5(z_loaned_config_t* config, const char* key, const char* json5) {
     // zenoh-c 1.0 uses zc_config_insert_json5
     return (int)zc_config_insert_json5(config, key, json5);
@@ -62,10 +167,12 @@ import "C"
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"iter"
+	"math"
 	"runtime"
 	"runtime/cgo"
-	"strings"
 	"sync"
 	"time"
 	"unsafe"
@@ -78,63 +185,61 @@ type cgoSession struct {
 
 	mu          sync.Mutex
 	closed      bool
-	publishers  map[KeyExpr]*cgoPublisher
+	publishers  map[publisherKey]*cgoPublisherDecl
 	subscribers []*cgoSubscriber
+	queryables  []*cgoQueryable
+	tokens      []*cgoLivelinessToken
+	keyExprs    map[KeyExpr]*cgoKeyExpr
+
+	// calls tracks the zenoh-c calls made without mu; see beginCall.
+	calls inFlight
+}
+
+// cgoKeyExpr is a key expression declared with z_declare_keyexpr,
+// shared by the DeclaredKeyExpr handles for it. The publishers,
+// subscribers and tokens declared with it, and the calls using it,
+// hold references too, so it outlives them.
+type cgoKeyExpr struct {
+	keyExpr C.z_owned_keyexpr_t
+	refs    int
 }
 
 // openSession creates a CGO-backed session.
 func openSession(cfg Config) (Session, error) {
 	var zconfig C.z_owned_config_t
-
-	// Configure based on mode
-	if cfg.Mode == ModeClient && len(cfg.Endpoints) > 0 {
-		// Build full JSON5 config for client mode
-		// Format: { "mode": "client", "connect": { "endpoints": ["tcp/..."] } }
-		quotedEndpoints := make([]string, len(cfg.Endpoints))
-		for i, ep := range cfg.Endpoints {
-			quotedEndpoints[i] = `"` + ep + `"`
-		}
-		configJSON := fmt.Sprintf(`{"mode":"client","connect":{"endpoints":[%s]}}`,
-			strings.Join(quotedEndpoints, ","))
-
-		cJSON := C.CString(configJSON)
-		defer C.free(unsafe.Pointer(cJSON))
-
-		// Create config from JSON5
+	if cfg.source != nil {
+		// Start from the loaded document, parsed by zenoh-c itself
+		cJSON := C.CString(cfg.source.text)
 		result := C.config_from_json5(&zconfig, cJSON)
+		C.free(unsafe.Pointer(cJSON))
 		if result < 0 {
-			// Fallback: try default config with manual endpoint insertion
-			C.z_config_default(&zconfig)
-
-			// Try inserting connect endpoints
-			connectJSON := fmt.Sprintf(`{"endpoints":[%s]}`, strings.Join(quotedEndpoints, ","))
-			cConnectJSON := C.CString(connectJSON)
-			cConnectKey := C.CString("connect")
-			defer C.free(unsafe.Pointer(cConnectJSON))
-			defer C.free(unsafe.Pointer(cConnectKey))
-
-			insertResult := C.config_insert_json5(C.z_config_loan(&zconfig), cConnectKey, cConnectJSON)
-			if insertResult < 0 {
-				// Log warning but continue - may work with defaults
-				// The router might be on localhost:7447
-			}
+			return nil, fmt.Errorf("invalid config: document rejected by zenoh-c: error code %d", result)
+		}
+	} else if C.z_config_default(&zconfig) < 0 {
+		return nil, errors.New("invalid config: cannot create default config")
+	}
+
+	// Apply typed fields and inserts on top
+	for _, e := range cfg.entries() {
+		if err := insertConfigEntry(&zconfig, e); err != nil {
+			C.z_config_drop(C.z_config_move(&zconfig))
+			return nil, err
 		}
-	} else {
-		// Peer mode or no endpoints - use defaults
-		C.z_config_default(&zconfig)
 	}
 
 	// Open session
 	var session C.z_owned_session_t
 	result := C.z_open(&session, C.z_config_move(&zconfig), nil)
 	if result < 0 {
+		// zenoh-c 1.0 returns the same code for rejected credentials and
+		// unreachable endpoints, so both surface as ErrConnectionFailed
 		return nil, fmt.Errorf("%w: error code %d", ErrConnectionFailed, result)
 	}
 
 	s := &cgoSession{
 		session:    session,
 		config:     cfg,
-		publishers: make(map[KeyExpr]*cgoPublisher),
+		publishers: make(map[publisherKey]*cgoPublisherDecl),
 	}
 
 	// Set finalizer for safety
@@ -145,7 +250,39 @@ func openSession(cfg Config) (Session, error) {
 	return s, nil
 }
 
-func (s *cgoSession) Publisher(keyExpr KeyExpr) (Publisher, error) {
+// insertConfigEntry applies a single entry with zc_config_insert_json5.
+func insertConfigEntry(zconfig *C.z_owned_config_t, e configEntry) error {
+	value, err := e.jsonValue()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	cKey := C.CString(e.path)
+	defer C.free(unsafe.Pointer(cKey))
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+
+	if result := C.config_insert_json5(C.z_config_loan_mut(zconfig), cKey, cValue); result < 0 {
+		return fmt.Errorf("invalid config: %s=%s rejected by zenoh-c: error code %d", e.path, e.displayValue(), result)
+	}
+	return nil
+}
+
+func (s *cgoSession) Publisher(keyExpr KeyExpr, opts ...Option) (Publisher, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+	qos, err := o.publisherQoS()
+	if err != nil {
+		return nil, err
+	}
+	key := publisherKey{
+		keyExpr:  keyExpr,
+		encoding: o.encodingOr(EncodingZenohBytes),
+		qos:      qos,
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -153,43 +290,207 @@ func (s *cgoSession) Publisher(keyExpr KeyExpr) (Publisher, error) {
 		return nil, ErrSessionClosed
 	}
 
-	// Check cache
-	if pub, ok := s.publishers[keyExpr]; ok {
-		return pub, nil
+	// Share the declaration of an open publisher with the same options
+	if decl, ok := s.publishers[key]; ok {
+		decl.refs++
+		return &cgoPublisher{session: s, key: key, decl: decl}, nil
 	}
 
-	// Create key expression
+	// Create key expression, or use the declared one
 	cKeyExpr := C.CString(string(keyExpr))
 	defer C.free(unsafe.Pointer(cKeyExpr))
 
-	var ke C.z_view_keyexpr_t
-	if C.z_view_keyexpr_from_str(&ke, cKeyExpr) < 0 {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyExpr, keyExpr)
+	var view C.z_view_keyexpr_t
+	ke, err := s.loanKeyExpr(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return nil, err
 	}
 
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	var pubOpts C.z_publisher_options_t
+	C.z_publisher_options_default(&pubOpts)
+	pubOpts.priority = C.z_priority_t(qos.Priority)
+	pubOpts.congestion_control = C.z_congestion_control_t(qos.CongestionControl)
+	pubOpts.is_express = C.bool(qos.Express)
+	if o.reliability != nil {
+		if C.publisher_options_set_reliability(&pubOpts, C.int(qos.reliability)) < 0 {
+			return nil, fmt.Errorf("%w: reliability requires zenoh-c built with the unstable API", ErrPublishFailed)
+		}
+	}
+	pubOpts.encoding = pinnedEncoding(&pinner, key.encoding)
+
 	// Declare publisher
-	var pub C.z_owned_publisher_t
+	decl := &cgoPublisherDecl{refs: 1}
 	result := C.z_declare_publisher(
 		C.z_session_loan(&s.session),
-		&pub,
-		C.z_view_keyexpr_loan(&ke),
-		nil,
+		&decl.pub,
+		ke,
+		&pubOpts,
 	)
 	if result < 0 {
 		return nil, fmt.Errorf("%w for %s: error code %d", ErrPublishFailed, keyExpr, result)
 	}
 
-	p := &cgoPublisher{
-		session: s,
-		keyExpr: keyExpr,
-		pub:     pub,
+	decl.declared = s.retainKeyExpr(keyExpr)
+	s.publishers[key] = decl
+	return &cgoPublisher{session: s, key: key, decl: decl}, nil
+}
+
+// releasePublisher releases the reference of a closed publisher handle
+// and undeclares the publisher with the last one.
+func (s *cgoSession) releasePublisher(key publisherKey, decl *cgoPublisherDecl) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// session.Close() took it
+	if s.closed || s.publishers[key] != decl {
+		return nil
+	}
+	decl.refs--
+	if decl.refs > 0 {
+		return nil
+	}
+
+	delete(s.publishers, key)
+	decl.calls.close()
+	if result := C.z_undeclare_publisher(C.z_publisher_move(&decl.pub)); result < 0 {
+		return fmt.Errorf("%w: undeclare publisher %s: error code %d", ErrPublishFailed, key.keyExpr, result)
+	}
+	if decl.declared != nil {
+		return s.releaseKeyExpr(key.keyExpr, decl.declared)
+	}
+	return nil
+}
+
+func (s *cgoSession) Put(keyExpr KeyExpr, data []byte, opts ...Option) error {
+	if err := keyExpr.Validate(); err != nil {
+		return err
+	}
+	o := collectOptions(opts)
+	qos, err := o.publisherQoS()
+	if err != nil {
+		return err
 	}
 
-	s.publishers[keyExpr] = p
-	return p, nil
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	var putOpts C.z_put_options_t
+	C.z_put_options_default(&putOpts)
+	putOpts.priority = C.z_priority_t(qos.Priority)
+	putOpts.congestion_control = C.z_congestion_control_t(qos.CongestionControl)
+	putOpts.is_express = C.bool(qos.Express)
+	if o.reliability != nil {
+		if C.put_options_set_reliability(&putOpts, C.int(qos.reliability)) < 0 {
+			return fmt.Errorf("%w: reliability requires zenoh-c built with the unstable API", ErrPublishFailed)
+		}
+	}
+
+	// Create key expression, or use the declared one
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	// Call zenoh-c without the lock: same-session subscribers run on
+	// this thread and may call into the session
+	var view C.z_view_keyexpr_t
+	ke, k, err := s.beginCall(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return err
+	}
+	defer s.endCall(keyExpr, k)
+
+	if o.encoding != nil {
+		putOpts.encoding = pinnedEncoding(&pinner, *o.encoding)
+	}
+	if o.attachment != nil {
+		putOpts.attachment = pinnedBytes(&pinner, o.attachment)
+	}
+
+	payload := bytesToC(data)
+	result := C.z_put(C.z_session_loan(&s.session), ke, C.z_bytes_move(&payload), &putOpts)
+	if result < 0 {
+		return fmt.Errorf("%w for %s: error code %d", ErrPublishFailed, keyExpr, result)
+	}
+	return nil
+}
+
+func (s *cgoSession) Delete(keyExpr KeyExpr, opts ...Option) error {
+	if err := keyExpr.Validate(); err != nil {
+		return err
+	}
+	o := collectOptions(opts)
+	if o.attachment != nil {
+		return errDeleteAttachment
+	}
+	qos, err := o.publisherQoS()
+	if err != nil {
+		return err
+	}
+
+	var delOpts C.z_delete_options_t
+	C.z_delete_options_default(&delOpts)
+	delOpts.priority = C.z_priority_t(qos.Priority)
+	delOpts.congestion_control = C.z_congestion_control_t(qos.CongestionControl)
+	delOpts.is_express = C.bool(qos.Express)
+	if o.reliability != nil {
+		if C.delete_options_set_reliability(&delOpts, C.int(qos.reliability)) < 0 {
+			return fmt.Errorf("%w: reliability requires zenoh-c built with the unstable API", ErrPublishFailed)
+		}
+	}
+
+	// Create key expression, or use the declared one
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	var view C.z_view_keyexpr_t
+	ke, k, err := s.beginCall(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return err
+	}
+	defer s.endCall(keyExpr, k)
+
+	result := C.z_delete(C.z_session_loan(&s.session), ke, &delOpts)
+	if result < 0 {
+		return fmt.Errorf("%w: delete %s: error code %d", ErrPublishFailed, keyExpr, result)
+	}
+	return nil
 }
 
 func (s *cgoSession) Subscribe(keyExpr KeyExpr, handler Handler) (Subscriber, error) {
+	return s.subscribe(keyExpr, handler, nil, nil)
+}
+
+func (s *cgoSession) SubscribeBorrowed(keyExpr KeyExpr, handler BorrowedHandler) (Subscriber, error) {
+	return s.subscribe(keyExpr, nil, handler, nil)
+}
+
+func (s *cgoSession) SubscribePooled(keyExpr KeyExpr, handler Handler) (Subscriber, error) {
+	return s.subscribe(keyExpr, nil, pooledHandler(handler), nil)
+}
+
+func (s *cgoSession) SubscribeChan(keyExpr KeyExpr, handler ChannelHandler) (ChannelSubscriber, error) {
+	queue, err := newSampleQueue(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	// The queue is the handler, so zenoh-c threads push to it directly
+	sub, err := s.subscribe(keyExpr, queue.push, nil, queue)
+	if err != nil {
+		return nil, err
+	}
+	return &channelSubscriber{Subscriber: sub, queue: queue}, nil
+}
+
+// subscribe declares a subscriber calling handler, or borrowed if set.
+// queue, if any, is closed with the subscriber.
+func (s *cgoSession) subscribe(keyExpr KeyExpr, handler Handler, borrowed BorrowedHandler, queue *sampleQueue) (*cgoSubscriber, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -197,77 +498,544 @@ func (s *cgoSession) Subscribe(keyExpr KeyExpr, handler Handler) (Subscriber, er
 		return nil, ErrSessionClosed
 	}
 
-	// Create key expression
+	// Create key expression, or use the declared one
 	cKeyExpr := C.CString(string(keyExpr))
 	defer C.free(unsafe.Pointer(cKeyExpr))
 
-	var ke C.z_view_keyexpr_t
-	if C.z_view_keyexpr_from_str(&ke, cKeyExpr) < 0 {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyExpr, keyExpr)
+	var view C.z_view_keyexpr_t
+	ke, err := s.loanKeyExpr(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create subscriber wrapper with cgo handle
 	sub := &cgoSubscriber{
-		session: s,
-		keyExpr: keyExpr,
-		handler: handler,
+		session:  s,
+		keyExpr:  keyExpr,
+		handler:  handler,
+		borrowed: borrowed,
+		queue:    queue,
 	}
 	sub.handle = cgo.NewHandle(sub)
 
 	// Create closure with our callback wrapper
-	closure := C.make_sample_closure(unsafe.Pointer(sub.handle))
+	closure := C.make_sample_closure(C.uintptr_t(sub.handle))
 
-	// Declare subscriber
+	// Declare subscriber. Ownership of the closure moves to zenoh-c
+	// even on failure, so the handle is released by the drop callback.
 	result := C.z_declare_subscriber(
 		C.z_session_loan(&s.session),
 		&sub.sub,
-		C.z_view_keyexpr_loan(&ke),
+		ke,
 		C.z_closure_sample_move(&closure),
 		nil,
 	)
 	if result < 0 {
-		sub.handle.Delete()
 		return nil, fmt.Errorf("%w for %s: error code %d", ErrSubscribeFailed, keyExpr, result)
 	}
 
+	sub.declared = s.retainKeyExpr(keyExpr)
 	s.subscribers = append(s.subscribers, sub)
 	return sub, nil
 }
 
-func (s *cgoSession) Get(ctx context.Context, keyExpr KeyExpr) ([]Sample, error) {
+// removeSubscriber removes sub from the session and reports whether it
+// was there, in which case the caller drops it and then calls
+// s.calls.exit(), as session.Close() waits for the drop; otherwise
+// session.Close() took it or is about to.
+func (s *cgoSession) removeSubscriber(sub *cgoSubscriber) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.subscribers {
+		if other == sub {
+			// Past s.calls.close(), Close drops the subscribers left
+			if !s.calls.enter() {
+				return false
+			}
+			s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *cgoSession) Get(ctx context.Context, keyExpr KeyExpr, opts ...Option) ([]Sample, error) {
+	c, err := s.query(ctx, keyExpr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.wait(ctx)
+}
+
+func (s *cgoSession) GetStream(ctx context.Context, keyExpr KeyExpr, opts ...Option) iter.Seq2[Reply, error] {
+	c, err := s.query(ctx, keyExpr, opts)
+	if err != nil {
+		return errorStream(err)
+	}
+	return c.stream(ctx)
+}
+
+// query sends a query with z_get. Its replies are gathered by the
+// returned collector, which is finished when zenoh-c drops the closure.
+func (s *cgoSession) query(ctx context.Context, keyExpr KeyExpr, opts []Option) (*replyCollector, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+
+	// Create key expression, or use the declared one
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	// Call zenoh-c without the lock: same-session queryables run on
+	// this thread and may call into the session
+	var view C.z_view_keyexpr_t
+	ke, k, err := s.beginCall(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return nil, err
+	}
+	defer s.endCall(keyExpr, k)
+
+	var cParams *C.char
+	if o.parameters != "" {
+		cParams = C.CString(o.parameters)
+		defer C.free(unsafe.Pointer(cParams))
+	}
+
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	var getOpts C.z_get_options_t
+	C.z_get_options_default(&getOpts)
+	if timeout, ok := queryTimeoutMs(ctx); ok {
+		getOpts.timeout_ms = C.uint64_t(timeout)
+	}
+	if o.payload != nil {
+		getOpts.payload = pinnedBytes(&pinner, o.payload)
+	}
+	if o.encoding != nil {
+		getOpts.encoding = pinnedEncoding(&pinner, *o.encoding)
+	}
+	if o.attachment != nil {
+		getOpts.attachment = pinnedBytes(&pinner, o.attachment)
+	}
+
+	// The collector is shared with the reply callbacks through a cgo handle.
+	// It is released by goReplyDropCallback once zenoh-c drops the closure.
+	c := newReplyCollector()
+	closure := C.make_reply_closure(C.uintptr_t(cgo.NewHandle(c)))
+
+	// Ownership of the closure moves to zenoh-c even on failure, so the
+	// handle is always released by the drop callback.
+	result := C.z_get(
+		C.z_session_loan(&s.session),
+		ke,
+		cParams,
+		C.z_closure_reply_move(&closure),
+		&getOpts,
+	)
+	if result < 0 {
+		return nil, fmt.Errorf("%w for %s: error code %d", ErrQueryFailed, keyExpr, result)
+	}
+	return c, nil
+}
+
+func (s *cgoSession) DeclareKeyExpr(keyExpr KeyExpr) (DeclaredKeyExpr, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.closed {
-		s.mu.Unlock()
 		return nil, ErrSessionClosed
 	}
+
+	// Declaring again only takes another reference
+	k := s.keyExprs[keyExpr]
+	if k == nil {
+		cKeyExpr := C.CString(string(keyExpr))
+		defer C.free(unsafe.Pointer(cKeyExpr))
+
+		var ke C.z_view_keyexpr_t
+		if C.z_view_keyexpr_from_str(&ke, cKeyExpr) < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidKeyExpr, keyExpr)
+		}
+
+		k = &cgoKeyExpr{}
+		result := C.z_declare_keyexpr(C.z_session_loan(&s.session), &k.keyExpr, C.z_view_keyexpr_loan(&ke))
+		if result < 0 {
+			return nil, fmt.Errorf("zenoh: declare key expression %s: error code %d", keyExpr, result)
+		}
+		if s.keyExprs == nil {
+			s.keyExprs = make(map[KeyExpr]*cgoKeyExpr)
+		}
+		s.keyExprs[keyExpr] = k
+	}
+	k.refs++
+
+	return &declaredKeyExpr{
+		session: s,
+		keyExpr: keyExpr,
+		undeclare: func() error {
+			return s.undeclareKeyExpr(keyExpr)
+		},
+	}, nil
+}
+
+// undeclareKeyExpr releases a reference taken by DeclareKeyExpr and
+// undeclares the key expression with the last one.
+func (s *cgoSession) undeclareKeyExpr(keyExpr KeyExpr) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Close already dropped it
+	k := s.keyExprs[keyExpr]
+	if s.closed || k == nil {
+		return nil
+	}
+	return s.releaseKeyExpr(keyExpr, k)
+}
+
+// releaseKeyExpr drops a reference on a declared key expression and
+// undeclares it with the last one, unless Close is about to drop it.
+// Must be called with s.mu held.
+func (s *cgoSession) releaseKeyExpr(keyExpr KeyExpr, k *cgoKeyExpr) error {
+	k.refs--
+	if k.refs > 0 || s.closed {
+		return nil
+	}
+
+	delete(s.keyExprs, keyExpr)
+	if result := C.z_undeclare_keyexpr(C.z_session_loan(&s.session), C.z_keyexpr_move(&k.keyExpr)); result < 0 {
+		return fmt.Errorf("zenoh: undeclare key expression %s: error code %d", keyExpr, result)
+	}
+	return nil
+}
+
+// beginCall prepares a zenoh-c call made without s.mu, which callbacks
+// run on the calling thread may need. It checks that the session is
+// open and loans keyExpr like loanKeyExpr, keeping a reference on the
+// declared key expression k, if any, until endCall. Close waits for
+// endCall before dropping the session.
+func (s *cgoSession) beginCall(keyExpr KeyExpr, cKeyExpr *C.char, view *C.z_view_keyexpr_t) (*C.z_loaned_keyexpr_t, *cgoKeyExpr, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, nil, ErrSessionClosed
+	}
+	ke, err := s.loanKeyExpr(keyExpr, cKeyExpr, view)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Close only closes calls after setting closed, so this succeeds
+	s.calls.enter()
+	return ke, s.retainKeyExpr(keyExpr), nil
+}
+
+// endCall ends a call started by beginCall.
+func (s *cgoSession) endCall(keyExpr KeyExpr, k *cgoKeyExpr) {
+	s.releaseRetained(keyExpr, k)
+	s.calls.exit()
+}
+
+// retainKeyExpr takes a reference on the declared key expression for
+// keyExpr, if any, for an entity or call that loaned it, and returns it.
+// Must be called with s.mu held.
+func (s *cgoSession) retainKeyExpr(keyExpr KeyExpr) *cgoKeyExpr {
+	k := s.keyExprs[keyExpr]
+	if k != nil {
+		k.refs++
+	}
+	return k
+}
+
+// releaseRetained releases a reference taken by retainKeyExpr, if k
+// is not nil.
+func (s *cgoSession) releaseRetained(keyExpr KeyExpr, k *cgoKeyExpr) {
+	if k == nil {
+		return
+	}
+	s.mu.Lock()
+	s.releaseKeyExpr(keyExpr, k)
 	s.mu.Unlock()
+}
 
-	// TODO: Implement z_get with reply handling
-	// This requires more complex callback handling for replies
-	return nil, fmt.Errorf("%w: Get not yet implemented", ErrQueryFailed)
+// loanKeyExpr returns keyExpr for zenoh-c: the declared key expression
+// if DeclareKeyExpr declared it, else a view of cKeyExpr stored in view.
+// Must be called with s.mu held.
+func (s *cgoSession) loanKeyExpr(keyExpr KeyExpr, cKeyExpr *C.char, view *C.z_view_keyexpr_t) (*C.z_loaned_keyexpr_t, error) {
+	if k := s.keyExprs[keyExpr]; k != nil {
+		return C.z_keyexpr_loan(&k.keyExpr), nil
+	}
+	if C.z_view_keyexpr_from_str(view, cKeyExpr) < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyExpr, keyExpr)
+	}
+	return C.z_view_keyexpr_loan(view), nil
 }
 
-func (s *cgoSession) Close() error {
+func (s *cgoSession) DeclareQueryable(keyExpr KeyExpr, handler QueryHandler) (Queryable, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if s.closed {
+		return nil, ErrSessionClosed
+	}
+
+	// Create key expression
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	var ke C.z_view_keyexpr_t
+	if C.z_view_keyexpr_from_str(&ke, cKeyExpr) < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyExpr, keyExpr)
+	}
+
+	// Create queryable wrapper with cgo handle
+	q := &cgoQueryable{
+		session: s,
+		keyExpr: keyExpr,
+		handler: handler,
+	}
+	q.handle = cgo.NewHandle(q)
+
+	// Create closure with our callback wrapper
+	closure := C.make_query_closure(C.uintptr_t(q.handle))
+
+	// Declare queryable. Ownership of the closure moves to zenoh-c
+	// even on failure, so the handle is released by the drop callback.
+	result := C.z_declare_queryable(
+		C.z_session_loan(&s.session),
+		&q.queryable,
+		C.z_view_keyexpr_loan(&ke),
+		C.z_closure_query_move(&closure),
+		nil,
+	)
+	if result < 0 {
+		return nil, fmt.Errorf("%w: declare queryable for %s: error code %d", ErrQueryFailed, keyExpr, result)
+	}
+
+	s.queryables = append(s.queryables, q)
+	return q, nil
+}
+
+func (s *cgoSession) DeclareLivelinessToken(keyExpr KeyExpr) (LivelinessToken, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Create key expression
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	// Same-session liveliness subscribers run on this thread; declare
+	// without the lock
+	var view C.z_view_keyexpr_t
+	ke, k, err := s.beginCall(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return nil, err
+	}
+	defer s.endCall(keyExpr, k)
+
+	t := &cgoLivelinessToken{
+		session: s,
+		keyExpr: keyExpr,
+	}
+
+	// Declare token
+	result := C.z_liveliness_declare_token(
+		C.z_session_loan(&s.session),
+		&t.token,
+		ke,
+		nil,
+	)
+	if result < 0 {
+		return nil, fmt.Errorf("%w: declare liveliness token for %s: error code %d", ErrPublishFailed, keyExpr, result)
+	}
+
+	// Close waits for this call before dropping the tokens
+	s.mu.Lock()
+	t.declared = s.retainKeyExpr(keyExpr)
+	s.tokens = append(s.tokens, t)
+	s.mu.Unlock()
+	return t, nil
+}
+
+func (s *cgoSession) SubscribeLiveliness(keyExpr KeyExpr, handler Handler, opts ...Option) (Subscriber, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+
+	// Create key expression
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	// History is replayed to the handler on this thread; declare without
+	// the lock
+	var view C.z_view_keyexpr_t
+	ke, k, err := s.beginCall(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return nil, err
+	}
+	defer s.endCall(keyExpr, k)
+
+	// Create subscriber wrapper with cgo handle
+	sub := &cgoSubscriber{
+		session: s,
+		keyExpr: keyExpr,
+		handler: handler,
+	}
+	sub.handle = cgo.NewHandle(sub)
+
+	// Create closure with our callback wrapper
+	closure := C.make_sample_closure(C.uintptr_t(sub.handle))
+
+	var subOpts C.z_liveliness_subscriber_options_t
+	C.z_liveliness_subscriber_options_default(&subOpts)
+	subOpts.history = C.bool(o.history)
+
+	// Declare liveliness subscriber. Ownership of the closure moves to
+	// zenoh-c even on failure, so the handle is released by the drop callback.
+	result := C.z_liveliness_declare_subscriber(
+		C.z_session_loan(&s.session),
+		&sub.sub,
+		ke,
+		C.z_closure_sample_move(&closure),
+		&subOpts,
+	)
+	if result < 0 {
+		return nil, fmt.Errorf("%w: liveliness for %s: error code %d", ErrSubscribeFailed, keyExpr, result)
+	}
+
+	// Close waits for this call, then closes the subscribers added since
+	s.mu.Lock()
+	sub.declared = s.retainKeyExpr(keyExpr)
+	s.subscribers = append(s.subscribers, sub)
+	s.mu.Unlock()
+	return sub, nil
+}
+
+func (s *cgoSession) GetLiveliness(ctx context.Context, keyExpr KeyExpr) ([]Sample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Create key expression
+	cKeyExpr := C.CString(string(keyExpr))
+	defer C.free(unsafe.Pointer(cKeyExpr))
+
+	// Same-session tokens answer on this thread; call without the lock
+	var view C.z_view_keyexpr_t
+	ke, k, err := s.beginCall(keyExpr, cKeyExpr, &view)
+	if err != nil {
+		return nil, err
+	}
+
+	var getOpts C.z_liveliness_get_options_t
+	C.z_liveliness_get_options_default(&getOpts)
+	if timeout, ok := queryTimeoutMs(ctx); ok {
+		getOpts.timeout_ms = C.uint32_t(min(timeout, math.MaxUint32))
+	}
+
+	// Same reply bridging as Get
+	c := newReplyCollector()
+	closure := C.make_reply_closure(C.uintptr_t(cgo.NewHandle(c)))
+
+	result := C.z_liveliness_get(
+		C.z_session_loan(&s.session),
+		ke,
+		C.z_closure_reply_move(&closure),
+		&getOpts,
+	)
+	s.endCall(keyExpr, k)
+	if result < 0 {
+		return nil, fmt.Errorf("%w: liveliness for %s: error code %d", ErrQueryFailed, keyExpr, result)
+	}
+
+	return c.wait(ctx)
+}
+
+// queryTimeoutMs converts the ctx deadline into a zenoh-c query timeout.
+// Returns false if ctx has no deadline.
+func queryTimeoutMs(ctx context.Context) (uint64, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	// Round up so a sub-millisecond budget is not sent as "no timeout"
+	timeout := time.Until(deadline)
+	ms := (timeout + time.Millisecond - 1) / time.Millisecond
+	if ms <= 0 {
+		ms = 1
+	}
+	return uint64(ms), true
+}
+
+func (s *cgoSession) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
 		return nil
 	}
 	s.closed = true
+	subscribers, publishers := s.subscribers, s.publishers
+	s.subscribers, s.publishers = nil, nil
+	s.mu.Unlock()
+
+	// Wait for running callbacks and puts without the lock, since they
+	// may call into the session, then drop the entities they use
+	for _, sub := range subscribers {
+		sub.close(true)
+	}
+	for _, decl := range publishers {
+		decl.calls.close()
+		C.z_publisher_drop(C.z_publisher_move(&decl.pub))
+	}
+
+	// Wait for session calls after closing subscribers, which wakes a
+	// call blocked on a full FIFO of this session. The remaining
+	// entities cannot change after that.
+	s.calls.close()
 
-	// Close all subscribers
-	for _, sub := range s.subscribers {
-		C.z_subscriber_drop(C.z_subscriber_move(&sub.sub))
-		sub.handle.Delete()
+	s.mu.Lock()
+	late, queryables, tokens, keyExprs := s.subscribers, s.queryables, s.tokens, s.keyExprs
+	s.subscribers, s.queryables, s.tokens, s.keyExprs = nil, nil, nil, nil
+	s.mu.Unlock()
+
+	// Drop them without the lock too, as undeclaring tokens notifies
+	// same-session liveliness subscribers on this thread. Liveliness
+	// subscribers declared by the calls just waited for come first.
+	for _, sub := range late {
+		sub.close(true)
+	}
+	for _, q := range queryables {
+		q.callbacks.close()
+		C.z_queryable_drop(C.z_queryable_move(&q.queryable))
+	}
+	for _, t := range tokens {
+		C.z_liveliness_token_drop(C.z_liveliness_token_move(&t.token))
 	}
-	s.subscribers = nil
 
-	// Close all publishers
-	for _, pub := range s.publishers {
-		C.z_publisher_drop(C.z_publisher_move(&pub.pub))
+	// Drop declared key expressions, now unused
+	for _, k := range keyExprs {
+		C.z_keyexpr_drop(C.z_keyexpr_move(&k.keyExpr))
 	}
-	s.publishers = nil
 
 	// Close session
 	C.z_session_drop(C.z_session_move(&s.session))
@@ -279,47 +1047,318 @@ func (s *cgoSession) Close() error {
 }
 
 func (s *cgoSession) Info() SessionInfo {
+	var id ZenohID
+	s.mu.Lock()
+	if !s.closed {
+		id = zenohIDFromC(C.z_info_zid(C.z_session_loan(&s.session)))
+	}
+	s.mu.Unlock()
+
 	return SessionInfo{
-		ID:        "cgo-session", // Could extract from z_info_zid
+		ID:        id,
 		Mode:      s.config.Mode,
 		Endpoints: s.config.Endpoints,
 		UsingCGO:  true,
 	}
 }
 
+func (s *cgoSession) RouterIDs() ([]ZenohID, error) {
+	return s.connectedIDs(func(closure *C.z_owned_closure_zid_t) C.z_result_t {
+		return C.z_info_routers_zid(C.z_session_loan(&s.session), C.z_closure_zid_move(closure))
+	})
+}
+
+func (s *cgoSession) PeerIDs() ([]ZenohID, error) {
+	return s.connectedIDs(func(closure *C.z_owned_closure_zid_t) C.z_result_t {
+		return C.z_info_peers_zid(C.z_session_loan(&s.session), C.z_closure_zid_move(closure))
+	})
+}
+
+// connectedIDs collects the IDs passed to a zid closure by info, which
+// calls it synchronously and drops it before returning.
+func (s *cgoSession) connectedIDs(info func(*C.z_owned_closure_zid_t) C.z_result_t) ([]ZenohID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+
+	ids := []ZenohID{}
+	h := cgo.NewHandle(&ids)
+	defer h.Delete()
+
+	closure := C.make_zid_closure(C.uintptr_t(h))
+	if result := info(&closure); result < 0 {
+		return nil, fmt.Errorf("session info: error code %d", result)
+	}
+	return ids, nil
+}
+
 //export goSampleCallback
 func goSampleCallback(sample *C.z_loaned_sample_t, context unsafe.Pointer) {
 	h := cgo.Handle(context)
 	sub := h.Value().(*cgoSubscriber)
 
+	// Skip samples racing with Close, which waits for this call otherwise
+	if !sub.callbacks.enter() {
+		return
+	}
+	defer sub.callbacks.exit()
+
+	// Call handler (in current goroutine - Zenoh manages threading)
+	if sub.borrowed == nil {
+		sub.handler(sampleFromC(sample))
+		return
+	}
+
+	// Lend the payload fragments in place; they are freed after the callback
+	payload := borrowPayload()
+	payloadFromC(payload, C.z_sample_payload(sample))
+	sub.borrowed(sampleHeaderFromC(sample), payload)
+	payload.release()
+}
+
+//export goSampleDropCallback
+func goSampleDropCallback(context unsafe.Pointer) {
+	cgo.Handle(context).Delete()
+}
+
+//export goZIDCallback
+func goZIDCallback(id *C.z_id_t, context unsafe.Pointer) {
+	ids := cgo.Handle(context).Value().(*[]ZenohID)
+	*ids = append(*ids, zenohIDFromC(*id))
+}
+
+//export goReplyCallback
+func goReplyCallback(reply *C.z_loaned_reply_t, context unsafe.Pointer) {
+	h := cgo.Handle(context)
+	c := h.Value().(*replyCollector)
+
+	if C.z_reply_is_ok(reply) {
+		c.addSample(sampleFromC(C.z_reply_ok(reply)))
+		return
+	}
+
+	replyErr := C.z_reply_err(reply)
+	c.addError(&ReplyError{Payload: bytesFromC(C.z_reply_err_payload(replyErr))})
+}
+
+//export goReplyDropCallback
+func goReplyDropCallback(context unsafe.Pointer) {
+	h := cgo.Handle(context)
+	c := h.Value().(*replyCollector)
+	h.Delete()
+
+	c.finish()
+}
+
+//export goQueryCallback
+func goQueryCallback(query *C.z_loaned_query_t, context unsafe.Pointer) {
+	h := cgo.Handle(context)
+	q := h.Value().(*cgoQueryable)
+
+	// Skip queries racing with Close, which waits for this call otherwise
+	if !q.callbacks.enter() {
+		return
+	}
+	defer q.callbacks.exit()
+
+	var params C.z_view_string_t
+	C.z_query_parameters(query, &params)
+
+	// The loaned query is only valid during this callback,
+	// so the replier is invalidated once the handler returns.
+	r := &cgoQueryReplier{query: query}
+	q.handler(Query{
+		KeyExpr:    keyExprFromC(C.z_query_keyexpr(query)),
+		Parameters: viewStringFromC(&params),
+		Payload:    bytesFromC(C.z_query_payload(query)),
+		Encoding:   encodingFromC(C.z_query_encoding(query)),
+		Attachment: bytesFromC(C.z_query_attachment(query)),
+		replier:    r,
+	})
+	r.finish()
+}
+
+//export goQueryDropCallback
+func goQueryDropCallback(context unsafe.Pointer) {
+	cgo.Handle(context).Delete()
+}
+
+// sampleFromC converts a loaned zenoh-c sample into a Sample.
+// The returned Sample owns copies of all data and outlives the callback.
+func sampleFromC(sample *C.z_loaned_sample_t) Sample {
+	s := sampleHeaderFromC(sample)
+	s.Payload = bytesFromC(C.z_sample_payload(sample))
+	return s
+}
+
+// sampleHeaderFromC converts everything but the payload of a sample.
+// Its key expression, encoding, attachment and source info are still
+// allocated per sample, in borrowed mode too.
+func sampleHeaderFromC(sample *C.z_loaned_sample_t) Sample {
+	return Sample{
+		KeyExpr:    keyExprFromC(C.z_sample_keyexpr(sample)),
+		Timestamp:  timestampFromC(C.z_sample_timestamp(sample)),
+		ReceivedAt: time.Now(),
+		Kind:       sampleKindFromC(C.z_sample_kind(sample)),
+		Encoding:   encodingFromC(C.z_sample_encoding(sample)),
+		QoS: QoS{
+			Priority:          Priority(C.z_sample_priority(sample)),
+			CongestionControl: CongestionControl(C.z_sample_congestion_control(sample)),
+			Express:           bool(C.z_sample_express(sample)),
+		},
+		Attachment: bytesFromC(C.z_sample_attachment(sample)),
+		SourceInfo: sourceInfoFromC(sample),
+	}
+}
+
+// timestampFromC converts a zenoh-c timestamp, which is NULL when the
+// sample was not stamped.
+func timestampFromC(ts *C.z_timestamp_t) Timestamp {
+	if ts == nil {
+		return Timestamp{}
+	}
+	return Timestamp{
+		NTP64: uint64(C.z_timestamp_ntp64_time(ts)),
+		ID:    zenohIDFromC(C.z_timestamp_id(ts)),
+	}
+}
+
+// sourceInfoFromC returns the source info of a sample, or nil if it
+// has none or zenoh-c was built without the unstable API.
+func sourceInfoFromC(sample *C.z_loaned_sample_t) *SourceInfo {
+	var zid C.z_id_t
+	var eid, sn C.uint32_t
+	if !C.sample_source_info(sample, &zid, &eid, &sn) {
+		return nil
+	}
+	info := &SourceInfo{ID: zenohIDFromC(zid), EntityID: uint32(eid), SN: uint32(sn)}
+	if info.ID.IsZero() {
+		return nil
+	}
+	return info
+}
+
+// sampleKindFromC converts a zenoh-c sample kind.
+func sampleKindFromC(kind C.z_sample_kind_t) SampleKind {
+	if kind == C.Z_SAMPLE_KIND_DELETE {
+		return SampleKindDelete
+	}
+	return SampleKindPut
+}
+
+// keyExprFromC copies a loaned zenoh-c key expression into a KeyExpr.
+func keyExprFromC(keyexpr *C.z_loaned_keyexpr_t) KeyExpr {
 	// Extract key expression using safe accessor
-	keyexpr := C.z_sample_keyexpr(sample)
 	var keystr C.z_view_string_t
 	C.z_keyexpr_as_view_string(keyexpr, &keystr)
+	return KeyExpr(viewStringFromC(&keystr))
+}
 
-	// Get key expression string using helper (handles version differences)
-	keyStrData := C.view_string_data(&keystr)
-	keyStrLen := C.view_string_len(&keystr)
-	keyExprStr := C.GoStringN(keyStrData, C.int(keyStrLen))
+// viewStringFromC copies a zenoh-c view string into a Go string.
+func viewStringFromC(s *C.z_view_string_t) string {
+	// Get string using helper (handles version differences)
+	data := C.view_string_data(s)
+	n := C.view_string_len(s)
+	return C.GoStringN(data, C.int(n))
+}
 
-	// Extract payload
-	payload := C.z_sample_payload(sample)
-	payloadLen := C.z_bytes_len(payload)
+// zenohIDFromC copies a zenoh-c ID.
+func zenohIDFromC(id C.z_id_t) ZenohID {
+	var zid ZenohID
+	for i := range zid {
+		zid[i] = byte(id.id[i])
+	}
+	return zid
+}
 
-	var payloadData []byte
-	if payloadLen > 0 {
-		payloadData = make([]byte, payloadLen)
-		// Use helper to read bytes safely
-		C.read_bytes_to_buffer(payload, (*C.uint8_t)(unsafe.Pointer(&payloadData[0])), payloadLen)
+// encodingFromC converts a loaned zenoh-c encoding through its string form.
+// Returns EncodingZenohBytes for a nil encoding.
+func encodingFromC(encoding *C.z_loaned_encoding_t) Encoding {
+	if encoding == nil {
+		return EncodingZenohBytes
 	}
 
-	s := Sample{
-		KeyExpr:   KeyExpr(keyExprStr),
-		Payload:   payloadData,
-		Timestamp: time.Now(),
-		Kind:      SampleKindPut,
+	var str C.z_owned_string_t
+	C.z_encoding_to_string(encoding, &str)
+	defer C.z_string_drop(C.z_string_move(&str))
+
+	loaned := C.z_string_loan(&str)
+	return ParseEncoding(C.GoStringN(C.z_string_data(loaned), C.int(C.z_string_len(loaned))))
+}
+
+// pinnedEncoding creates an owned zenoh-c encoding in pinned Go memory,
+// for use in an options struct (see pinnedBytes).
+func pinnedEncoding(pinner *runtime.Pinner, e Encoding) *C.z_moved_encoding_t {
+	cEncoding := C.CString(e.String())
+	defer C.free(unsafe.Pointer(cEncoding))
+
+	encoding := new(C.z_owned_encoding_t)
+	C.z_encoding_from_str(encoding, cEncoding)
+	pinner.Pin(encoding)
+	return C.z_encoding_move(encoding)
+}
+
+// bytesToC copies data into newly owned zenoh-c bytes.
+// The caller must move the result into a zenoh-c call or drop it.
+func bytesToC(data []byte) C.z_owned_bytes_t {
+	var bytes C.z_owned_bytes_t
+	if len(data) == 0 {
+		C.z_bytes_empty(&bytes)
+		return bytes
 	}
 
-	// Call handler (in current goroutine - Zenoh manages threading)
-	sub.handler(s)
+	C.z_bytes_copy_from_buf(
+		&bytes,
+		(*C.uint8_t)(unsafe.Pointer(&data[0])),
+		C.size_t(len(data)),
+	)
+	return bytes
+}
+
+// pinnedBytes copies data into owned zenoh-c bytes held in pinned Go memory.
+// The moved pointer can then be stored in an options struct, which is itself
+// Go memory passed to C and so may only reference pinned Go memory.
+func pinnedBytes(pinner *runtime.Pinner, data []byte) *C.z_moved_bytes_t {
+	bytes := new(C.z_owned_bytes_t)
+	*bytes = bytesToC(data)
+	pinner.Pin(bytes)
+	return C.z_bytes_move(bytes)
+}
+
+// payloadFromC adds the fragments of bytes to p without copying them.
+// They stay valid while bytes is loaned.
+func payloadFromC(p *Payload, bytes *C.z_loaned_bytes_t) {
+	if bytes == nil {
+		return
+	}
+
+	it := C.z_bytes_get_slice_iterator(bytes)
+	var view C.z_view_slice_t
+	for C.z_bytes_slice_iterator_next(&it, &view) {
+		slice := C.z_view_slice_loan(&view)
+		if n := C.z_slice_len(slice); n > 0 {
+			p.add(unsafe.Slice((*byte)(unsafe.Pointer(C.z_slice_data(slice))), n))
+		}
+	}
+}
+
+// bytesFromC copies loaned zenoh-c bytes into a Go slice.
+// Returns nil for nil or empty bytes.
+func bytesFromC(bytes *C.z_loaned_bytes_t) []byte {
+	if bytes == nil {
+		return nil
+	}
+
+	n := C.z_bytes_len(bytes)
+	if n == 0 {
+		return nil
+	}
+
+	data := make([]byte, n)
+	// Use helper to read bytes safely
+	C.read_bytes_to_buffer(bytes, (*C.uint8_t)(unsafe.Pointer(&data[0])), n)
+	return data
 }
@@ -3,8 +3,12 @@
 package zenoh
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"iter"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,22 +16,51 @@ import (
 // Used when CGO is disabled.
 type mockSession struct {
 	config Config
+	id     ZenohID
+	clock  *HLC
 
 	mu          sync.RWMutex
 	closed      bool
-	subscribers map[KeyExpr][]Handler
-	messages    []Sample
+	subscribers KeyExprTree[*mockSubscriber]
+	queryables  []*mockQueryable
+
+	messages []Sample
+
+	// publishers counts the open publishers of each declaration, like
+	// the shared declarations of the cgo backend.
+	publishers map[publisherKey]int
+
+	// entities numbers publishers for their SourceInfo; putSN counts
+	// the samples of Session.Put and Session.Delete, entity 0.
+	entities atomic.Uint32
+	putSN    atomic.Uint32
 }
 
 // openSession creates a mock session (no CGO).
 func openSession(cfg Config) (Session, error) {
+	id := nextMockID()
 	return &mockSession{
-		config:      cfg,
-		subscribers: make(map[KeyExpr][]Handler),
+		config: cfg,
+		id:     id,
+		clock:  NewHLC(id),
 	}, nil
 }
 
-func (s *mockSession) Publisher(keyExpr KeyExpr) (Publisher, error) {
+func (s *mockSession) Publisher(keyExpr KeyExpr, opts ...Option) (Publisher, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+	qos, err := o.publisherQoS()
+	if err != nil {
+		return nil, err
+	}
+	key := publisherKey{
+		keyExpr:  keyExpr,
+		encoding: o.encodingOr(EncodingZenohBytes),
+		qos:      qos,
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -35,10 +68,80 @@ func (s *mockSession) Publisher(keyExpr KeyExpr) (Publisher, error) {
 		return nil, ErrSessionClosed
 	}
 
-	return &mockPublisher{session: s, keyExpr: keyExpr}, nil
+	if s.publishers == nil {
+		s.publishers = make(map[publisherKey]int)
+	}
+	s.publishers[key]++
+
+	return &mockPublisher{
+		session:  s,
+		key:      key,
+		keyExpr:  keyExpr,
+		encoding: key.encoding,
+		qos:      qos.QoS,
+		entityID: s.entities.Add(1),
+		sn:       new(atomic.Uint32),
+	}, nil
+}
+
+// releasePublisher is called by mockPublisher.Close; the declaration
+// is forgotten with its last publisher.
+func (s *mockSession) releasePublisher(key publisherKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.publishers[key] <= 1 {
+		delete(s.publishers, key)
+		return
+	}
+	s.publishers[key]--
+}
+
+func (s *mockSession) Put(keyExpr KeyExpr, data []byte, opts ...Option) error {
+	pub, err := s.oneShotPublisher(keyExpr, opts)
+	if err != nil {
+		return err
+	}
+	return pub.Put(data, opts...)
+}
+
+func (s *mockSession) Delete(keyExpr KeyExpr, opts ...Option) error {
+	pub, err := s.oneShotPublisher(keyExpr, opts)
+	if err != nil {
+		return err
+	}
+	return pub.Delete(opts...)
+}
+
+// oneShotPublisher returns an undeclared publisher for Put and Delete,
+// so they share the dispatch of mockPublisher.
+func (s *mockSession) oneShotPublisher(keyExpr KeyExpr, opts []Option) (*mockPublisher, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+	qos, err := o.publisherQoS()
+	if err != nil {
+		return nil, err
+	}
+	if s.isClosed() {
+		return nil, ErrSessionClosed
+	}
+
+	return &mockPublisher{
+		session:  s,
+		keyExpr:  keyExpr,
+		encoding: EncodingZenohBytes,
+		qos:      qos.QoS,
+		sn:       &s.putSN,
+	}, nil
 }
 
 func (s *mockSession) Subscribe(keyExpr KeyExpr, handler Handler) (Subscriber, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -46,74 +149,347 @@ func (s *mockSession) Subscribe(keyExpr KeyExpr, handler Handler) (Subscriber, e
 		return nil, ErrSessionClosed
 	}
 
-	s.subscribers[keyExpr] = append(s.subscribers[keyExpr], handler)
-	return &mockSubscriber{session: s, keyExpr: keyExpr, handler: handler}, nil
+	sub := &mockSubscriber{session: s, keyExpr: keyExpr, handler: handler}
+	s.subscribers.Insert(keyExpr, sub)
+	return sub, nil
 }
 
-func (s *mockSession) Get(ctx context.Context, keyExpr KeyExpr) ([]Sample, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *mockSession) SubscribeBorrowed(keyExpr KeyExpr, handler BorrowedHandler) (Subscriber, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	if s.closed {
 		return nil, ErrSessionClosed
 	}
 
-	var results []Sample
+	sub := &mockSubscriber{session: s, keyExpr: keyExpr, borrowed: handler}
+	s.subscribers.Insert(keyExpr, sub)
+	return sub, nil
+}
+
+func (s *mockSession) SubscribePooled(keyExpr KeyExpr, handler Handler) (Subscriber, error) {
+	return s.SubscribeBorrowed(keyExpr, pooledHandler(handler))
+}
+
+func (s *mockSession) SubscribeChan(keyExpr KeyExpr, handler ChannelHandler) (ChannelSubscriber, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	queue, err := newSampleQueue(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+
+	sub := &mockSubscriber{session: s, keyExpr: keyExpr, queue: queue}
+	s.subscribers.Insert(keyExpr, sub)
+	return &channelSubscriber{Subscriber: sub, queue: queue}, nil
+}
+
+func (s *mockSession) Get(ctx context.Context, keyExpr KeyExpr, opts ...Option) ([]Sample, error) {
+	c, err := s.query(ctx, keyExpr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.wait(ctx)
+}
+
+func (s *mockSession) GetStream(ctx context.Context, keyExpr KeyExpr, opts ...Option) iter.Seq2[Reply, error] {
+	c, err := s.query(ctx, keyExpr, opts)
+	if err != nil {
+		return errorStream(err)
+	}
+	return c.stream(ctx)
+}
+
+// query starts a query whose replies are gathered by the returned collector.
+func (s *mockSession) query(ctx context.Context, keyExpr KeyExpr, opts []Option) (*replyCollector, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return nil, ErrSessionClosed
+	}
+
+	c := newReplyCollector()
+
+	// Published samples act as an in-process storage
 	for _, msg := range s.messages {
-		if matchKeyExpr(keyExpr, msg.KeyExpr) {
-			results = append(results, msg)
+		if Intersects(keyExpr, msg.KeyExpr) {
+			c.addSample(msg)
 		}
 	}
-	return results, nil
+
+	var queryables []*mockQueryable
+	for _, q := range s.queryables {
+		if Intersects(q.keyExpr, keyExpr) && q.calls.enter() {
+			queryables = append(queryables, q)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Route the query to in-process queryables, each in its own goroutine
+	// like zenoh-c does, and finish once every handler has returned.
+	var wg sync.WaitGroup
+	for _, q := range queryables {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer q.calls.exit()
+			r := &mockQueryReplier{keyExpr: keyExpr, collector: c}
+			q.handler(Query{
+				KeyExpr:    keyExpr,
+				Parameters: o.parameters,
+				Payload:    o.payload,
+				Encoding:   o.encodingOr(EncodingZenohBytes),
+				Attachment: o.attachment,
+				replier:    r,
+			})
+			r.finish()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		c.finish()
+	}()
+	return c, nil
 }
 
-func (s *mockSession) Close() error {
+func (s *mockSession) DeclareKeyExpr(keyExpr KeyExpr) (DeclaredKeyExpr, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	if s.isClosed() {
+		return nil, ErrSessionClosed
+	}
+
+	// Nothing goes over the wire, so there is nothing to undeclare
+	return &declaredKeyExpr{
+		session:   s,
+		keyExpr:   keyExpr,
+		undeclare: func() error { return nil },
+	}, nil
+}
+
+func (s *mockSession) DeclareQueryable(keyExpr KeyExpr, handler QueryHandler) (Queryable, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+
+	q := &mockQueryable{session: s, keyExpr: keyExpr, handler: handler}
+	s.queryables = append(s.queryables, q)
+	return q, nil
+}
+
+func (s *mockSession) DeclareLivelinessToken(keyExpr KeyExpr) (LivelinessToken, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
+	if s.isClosed() {
+		return nil, ErrSessionClosed
+	}
+
+	// Handlers are notified synchronously, so the session lock is not held
+	t := &mockLivelinessToken{session: s, keyExpr: keyExpr}
+	mockLiveliness.declareToken(t)
+
+	// The session may have closed concurrently, after its tokens were dropped
+	if s.isClosed() {
+		t.Close()
+		return nil, ErrSessionClosed
+	}
+	return t, nil
+}
+
+func (s *mockSession) SubscribeLiveliness(keyExpr KeyExpr, handler Handler, opts ...Option) (Subscriber, error) {
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+	o := collectOptions(opts)
+
+	if s.isClosed() {
+		return nil, ErrSessionClosed
+	}
+
+	sub := &mockLivelinessSubscriber{session: s, keyExpr: keyExpr, handler: handler}
+	mockLiveliness.subscribe(sub, o.history)
+
+	if s.isClosed() {
+		sub.Close()
+		return nil, ErrSessionClosed
+	}
+	return sub, nil
+}
+
+func (s *mockSession) GetLiveliness(ctx context.Context, keyExpr KeyExpr) ([]Sample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+	if err := keyExpr.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+
+	return mockLiveliness.get(keyExpr), nil
+}
+
+func (s *mockSession) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
 	s.closed = true
-	s.subscribers = nil
+	subscribers, queryables := s.subscribers, s.queryables
+	s.subscribers = KeyExprTree[*mockSubscriber]{}
+	s.queryables = nil
+	s.publishers = nil
+	s.mu.Unlock()
+
+	// Wait for deliveries outside the lock, since handlers may call
+	// into the session
+	for _, sub := range subscribers.All() {
+		sub.queue.close()
+		sub.calls.close()
+	}
+	for _, q := range queryables {
+		q.calls.close()
+	}
+
+	// Tokens of this session disappear for everyone else
+	mockLiveliness.closeSession(s)
 	return nil
 }
 
 func (s *mockSession) Info() SessionInfo {
+	var id ZenohID
+	if !s.isClosed() {
+		id = s.id
+	}
 	return SessionInfo{
-		ID:        "mock-session",
+		ID:        id,
 		Mode:      s.config.Mode,
 		Endpoints: s.config.Endpoints,
 		UsingCGO:  false,
 	}
 }
 
-// publish is called by mockPublisher to deliver samples.
-func (s *mockSession) publish(keyExpr KeyExpr, data []byte, kind SampleKind) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// RouterIDs returns no routers: mock sessions are not connected to any.
+func (s *mockSession) RouterIDs() ([]ZenohID, error) {
+	if s.isClosed() {
+		return nil, ErrSessionClosed
+	}
+	return []ZenohID{}, nil
+}
 
-	if s.closed {
-		return
+// PeerIDs returns no peers: mock sessions are not connected to each other.
+func (s *mockSession) PeerIDs() ([]ZenohID, error) {
+	if s.isClosed() {
+		return nil, ErrSessionClosed
 	}
+	return []ZenohID{}, nil
+}
 
-	sample := Sample{
-		KeyExpr:   keyExpr,
-		Payload:   data,
-		Timestamp: time.Now(),
-		Kind:      kind,
+// mockSessionCount numbers the sessions opened by the mock backend.
+var mockSessionCount atomic.Uint64
+
+// nextMockID returns a deterministic ID for a new mock session: the
+// n-th session opened in the process has ID n.
+func nextMockID() ZenohID {
+	var id ZenohID
+	binary.LittleEndian.PutUint64(id[:], mockSessionCount.Add(1))
+	return id
+}
+
+func (s *mockSession) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+// publish is called by mockPublisher to deliver samples. Like the cgo
+// backend, it fails with ErrSessionClosed once the session is closed.
+// The sample is timestamped by the session's HLC on delivery, as by a
+// Zenoh node with timestamping enabled, so the timestamps of a session
+// are strictly increasing.
+func (s *mockSession) publish(sample Sample) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSessionClosed
 	}
 
+	sample.Timestamp = s.clock.Now()
+	sample.ReceivedAt = time.Now()
+
+	// Take a copy, as sending would, so the publisher can reuse its buffer
+	sample.Payload = bytes.Clone(sample.Payload)
+	keyExpr := sample.KeyExpr
+
 	// Store for Get queries
 	s.messages = append(s.messages, sample)
 
-	// Notify matching subscribers
-	for pattern, handlers := range s.subscribers {
-		if matchKeyExpr(pattern, keyExpr) {
-			for _, h := range handlers {
-				// Call handler in goroutine to avoid blocking
-				go h(sample)
-			}
+	// Notify matching subscribers; each delivery is entered under the
+	// lock, so Close waits for the samples published before it
+	var queues []*mockSubscriber
+	s.subscribers.Match(keyExpr, func(sub *mockSubscriber) bool {
+		if !sub.calls.enter() {
+			return true
+		}
+		if sub.queue != nil {
+			queues = append(queues, sub)
+			return true
 		}
+		// Call handler in goroutine to avoid blocking
+		go func() {
+			defer sub.calls.exit()
+			sub.deliver(sample)
+		}()
+		return true
+	})
+	s.mu.Unlock()
+
+	// Queue in order on the publishing goroutine, outside the lock,
+	// so a full FIFO holds back the publisher like a congested link
+	for _, sub := range queues {
+		queued := sample
+		queued.Payload = bytes.Clone(sample.Payload)
+		sub.queue.push(queued)
+		sub.calls.exit()
 	}
+	return nil
 }
 
-// matchKeyExpr and matchParts are defined in keyexpr.go
+// KeyExprTree is defined in keytree.go
 
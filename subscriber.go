@@ -18,6 +18,9 @@ package zenoh
 type Subscriber interface {
 	// Close stops the subscription and releases resources.
 	// After Close, no more samples will be delivered to the handler.
+	// Close waits for handler calls in progress to return, so it must
+	// not be called from the handler itself. It is safe to call
+	// concurrently with session.Close and more than once.
 	Close() error
 }
 
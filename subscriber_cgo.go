@@ -10,35 +10,45 @@ import "C"
 import "runtime/cgo"
 
 // cgoSubscriber wraps a native Zenoh subscriber.
+//
+// The native subscriber is dropped by whoever removes it from the
+// session: Close, or session.Close(). The cgo handle is deleted when
+// zenoh-c drops the closure, after the last callback.
 type cgoSubscriber struct {
-	session *cgoSession
-	keyExpr KeyExpr
-	handler Handler
-	sub     C.z_owned_subscriber_t
-	handle  cgo.Handle
-	closed  bool
+	session  *cgoSession
+	keyExpr  KeyExpr
+	handler  Handler
+	borrowed BorrowedHandler
+	queue    *sampleQueue
+	sub      C.z_owned_subscriber_t
+	handle   cgo.Handle
+
+	// callbacks tracks the handler calls in progress.
+	callbacks inFlight
+
+	// declared is the declared key expression it was declared with,
+	// if any, referenced until it is dropped.
+	declared *cgoKeyExpr
 }
 
 func (s *cgoSubscriber) Close() error {
-	if s.closed {
-		return nil
+	owned := s.session.removeSubscriber(s)
+	s.close(owned)
+	if owned {
+		s.session.releaseRetained(s.keyExpr, s.declared)
+		s.session.calls.exit()
 	}
-	s.closed = true
-
-	// Drop the subscriber
-	C.z_subscriber_drop(C.z_subscriber_move(&s.sub))
-
-	// Delete the cgo handle
-	s.handle.Delete()
-
 	return nil
 }
 
+// close stops delivery, waits for running callbacks, and drops the
+// native subscriber if owned.
+func (s *cgoSubscriber) close(owned bool) {
+	// Wake a callback blocked on a full FIFO, so waiting on it cannot block
+	s.queue.close()
+	s.callbacks.close()
 
-
-
-
-
-
-
-
+	if owned {
+		C.z_subscriber_drop(C.z_subscriber_move(&s.sub))
+	}
+}
@@ -2,41 +2,48 @@
 
 package zenoh
 
+import "bytes"
+
 // mockSubscriber implements Subscriber for testing.
+// Samples go to handler or borrowed, or to queue for channel subscribers.
 type mockSubscriber struct {
-	session *mockSession
-	keyExpr KeyExpr
-	handler Handler
-	closed  bool
+	session  *mockSession
+	keyExpr  KeyExpr
+	handler  Handler
+	borrowed BorrowedHandler
+	queue    *sampleQueue
+
+	// calls tracks the deliveries in progress, entered by publish.
+	calls inFlight
 }
 
-func (s *mockSubscriber) Close() error {
-	if s.closed {
-		return nil
-	}
-	s.closed = true
-
-	// Remove handler from session
-	s.session.mu.Lock()
-	defer s.session.mu.Unlock()
-
-	handlers := s.session.subscribers[s.keyExpr]
-	for i, h := range handlers {
-		// Compare function pointers (this is a simplification)
-		if &h == &s.handler {
-			s.session.subscribers[s.keyExpr] = append(handlers[:i], handlers[i+1:]...)
-			break
-		}
+// deliver passes a published sample to the handler. Like the cgo
+// backend, plain handlers get their own copy of the payload, while
+// borrowed handlers read the published one in place.
+func (s *mockSubscriber) deliver(sample Sample) {
+	if s.borrowed == nil {
+		sample.Payload = bytes.Clone(sample.Payload)
+		s.handler(sample)
+		return
 	}
 
-	return nil
+	payload := borrowPayload()
+	payload.add(sample.Payload)
+	sample.Payload = nil
+	s.borrowed(sample, payload)
+	payload.release()
 }
 
+func (s *mockSubscriber) Close() error {
+	// Unblock a publisher waiting on a full FIFO before taking the lock
+	s.queue.close()
 
+	// Remove subscriber from session
+	s.session.mu.Lock()
+	s.session.subscribers.Remove(s.keyExpr, s)
+	s.session.mu.Unlock()
 
-
-
-
-
-
-
+	// Wait for samples already dispatched, like the cgo backend
+	s.calls.close()
+	return nil
+}
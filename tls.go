@@ -0,0 +1,164 @@
+package zenoh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig configures encrypted links for "tls/" and "quic/" endpoints.
+// QUIC links use the same settings as TLS.
+//
+// Paths point to PEM files. Fields left empty keep the value of a
+// loaded configuration document, if any.
+type TLSConfig struct {
+	// RootCACertificate verifies the certificate of the remote side.
+	// Required to connect to tls/ and quic/ endpoints, and to verify
+	// client certificates when EnableMTLS is set.
+	RootCACertificate string
+
+	// ListenCertificate and ListenPrivateKey identify this node on
+	// tls/ and quic/ listen endpoints.
+	ListenCertificate string
+	ListenPrivateKey  string
+
+	// ConnectCertificate and ConnectPrivateKey identify this node to
+	// the remote side when mutual TLS is enabled.
+	ConnectCertificate string
+	ConnectPrivateKey  string
+
+	// EnableMTLS requires both sides to present a certificate.
+	EnableMTLS bool
+
+	// SkipNameVerification disables checking that the remote certificate
+	// matches the endpoint host name, e.g. when connecting by IP address.
+	SkipNameVerification bool
+}
+
+// tlsConfigPath is the configuration section shared by TLS and QUIC links.
+const tlsConfigPath = "transport/link/tls"
+
+// WithTLS returns a copy of the config with the specified TLS settings.
+func (c Config) WithTLS(tls TLSConfig) Config {
+	c.TLS = &tls
+	return c
+}
+
+// entries returns the configuration values for the set fields.
+func (t *TLSConfig) entries() []configEntry {
+	if t == nil {
+		return nil
+	}
+
+	var entries []configEntry
+	add := func(key string, value any) {
+		entries = append(entries, configEntry{path: tlsConfigPath + "/" + key, value: value})
+	}
+	for _, f := range []struct{ key, value string }{
+		{"root_ca_certificate", t.RootCACertificate},
+		{"listen_certificate", t.ListenCertificate},
+		{"listen_private_key", t.ListenPrivateKey},
+		{"connect_certificate", t.ConnectCertificate},
+		{"connect_private_key", t.ConnectPrivateKey},
+	} {
+		if f.value != "" {
+			add(f.key, f.value)
+		}
+	}
+	if t.EnableMTLS {
+		add("enable_mtls", true)
+	}
+	if t.SkipNameVerification {
+		add("verify_name_on_connect", false)
+	}
+	return entries
+}
+
+// validateTLS checks that every tls/ and quic/ endpoint of a configuration
+// document has the certificate material it needs, and that configured
+// certificate files exist.
+func validateTLS(doc map[string]any) error {
+	settings := map[string]any{}
+	if v, ok := lookupConfigPath(doc, tlsConfigPath).(map[string]any); ok {
+		settings = v
+	}
+	mtls := settings["enable_mtls"] == true
+
+	for _, key := range []string{
+		"root_ca_certificate",
+		"listen_certificate",
+		"listen_private_key",
+		"connect_certificate",
+		"connect_private_key",
+	} {
+		if path, ok := settings[key].(string); ok && path != "" {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("tls %s: %w", key, err)
+			}
+		}
+	}
+
+	for _, ep := range stringList(lookupConfigPath(doc, "connect/endpoints")) {
+		required := []string{"root_ca_certificate"}
+		if mtls {
+			required = append(required, "connect_certificate", "connect_private_key")
+		}
+		if err := checkEndpointTLS(ep, settings, required); err != nil {
+			return err
+		}
+	}
+
+	for _, ep := range stringList(lookupConfigPath(doc, "listen/endpoints")) {
+		required := []string{"listen_certificate", "listen_private_key"}
+		if mtls {
+			required = append(required, "root_ca_certificate")
+		}
+		if err := checkEndpointTLS(ep, settings, required); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEndpointTLS checks that a tls/ or quic/ endpoint has every required
+// setting, either in the TLS section or in its own "#key=value" config.
+// Other endpoints are accepted as is.
+func checkEndpointTLS(endpoint string, settings map[string]any, required []string) error {
+	if !strings.HasPrefix(endpoint, "tls/") && !strings.HasPrefix(endpoint, "quic/") {
+		return nil
+	}
+
+	local := map[string]bool{}
+	if _, cfg, ok := strings.Cut(endpoint, "#"); ok {
+		for _, kv := range strings.Split(cfg, ";") {
+			key, _, _ := strings.Cut(kv, "=")
+			local[key] = true
+		}
+	}
+
+	var missing []string
+	for _, key := range required {
+		_, hasPath := settings[key]
+		_, hasBase64 := settings[key+"_base64"]
+		if !hasPath && !hasBase64 && !local[key] && !local[key+"_base64"] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("endpoint %s requires %s", endpoint, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// lookupConfigPath returns the value at a slash-separated path, or nil.
+func lookupConfigPath(doc map[string]any, path string) any {
+	var node any = doc
+	for _, key := range strings.Split(path, "/") {
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = obj[key]
+	}
+	return node
+}
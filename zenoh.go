@@ -31,6 +31,9 @@ const (
 
 	// ModeClient requires explicit endpoints to connect to
 	ModeClient = "client"
+
+	// ModeRouter operates as a Zenoh router, routing for peers and clients
+	ModeRouter = "router"
 )
 
 
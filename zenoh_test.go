@@ -1,8 +1,18 @@
 package zenoh
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -23,6 +33,35 @@ func TestOpenSession(t *testing.T) {
 	}
 }
 
+func TestSessionIdentity(t *testing.T) {
+	a, _ := Open(DefaultConfig())
+	defer a.Close()
+	b, _ := Open(DefaultConfig())
+
+	idA, idB := a.Info().ID, b.Info().ID
+	if idA.IsZero() || idB.IsZero() || idA == idB {
+		t.Fatalf("IDs %s and %s, want distinct non-zero IDs", idA, idB)
+	}
+
+	routers, err := a.RouterIDs()
+	if err != nil || len(routers) != 0 {
+		t.Errorf("RouterIDs() = %v, %v; want none", routers, err)
+	}
+
+	b.Close()
+	if id := b.Info().ID; !id.IsZero() {
+		t.Errorf("ID after Close = %s, want zero", id)
+	}
+	if _, err := b.PeerIDs(); err != ErrSessionClosed {
+		t.Errorf("PeerIDs() after Close error = %v, want ErrSessionClosed", err)
+	}
+
+	id := ZenohID{0x0f, 0xa1, 0x02}
+	if got := id.String(); got != "2a10f" {
+		t.Errorf("String() = %q, want %q", got, "2a10f")
+	}
+}
+
 func TestConfigValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -66,6 +105,246 @@ func TestConfigValidation(t *testing.T) {
 	}
 }
 
+func TestConfigJSON5(t *testing.T) {
+	scouting := PeerConfig("tcp/0.0.0.0:7447")
+	scouting.DisableMulticastScouting = true
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{
+			name:   "client",
+			config: ClientConfig("tcp/192.168.68.80:7447"),
+			want:   `{"connect":{"endpoints":["tcp/192.168.68.80:7447"],"timeout_ms":5000},"mode":"client"}`,
+		},
+		{
+			name:   "peer with listen and scouting",
+			config: scouting,
+			want:   `{"connect":{"timeout_ms":5000},"listen":{"endpoints":["tcp/0.0.0.0:7447"]},"mode":"peer","scouting":{"multicast":{"enabled":false}}}`,
+		},
+		{
+			name:   "escaped endpoint",
+			config: ClientConfig(`tcp/host"],"mode":"router:7447`),
+			want:   `{"connect":{"endpoints":["tcp/host\"],\"mode\":\"router:7447"],"timeout_ms":5000},"mode":"client"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.JSON5()
+			if err != nil {
+				t.Fatalf("JSON5() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("JSON5() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromFile(t *testing.T) {
+	const doc = `{
+		// Router deployment
+		mode: 'client',
+		connect: { endpoints: ["tcp/10.0.0.1:7447",], },
+		timestamping: { enabled: { router: true, peer: true, client: true } },
+	}`
+	path := filepath.Join(t.TempDir(), "zenoh.json5")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvConfig, path)
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv failed: %v", err)
+	}
+	if cfg.Mode != ModeClient || len(cfg.Endpoints) != 1 || cfg.Endpoints[0] != "tcp/10.0.0.1:7447" {
+		t.Errorf("Typed fields not loaded from document: %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	// Typed fields override the document, inserts are applied last
+	cfg.Endpoints = []string{"tcp/10.0.0.2:7447"}
+	if err := cfg.Insert("scouting/delay", "500"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	got, err := cfg.JSON5()
+	if err != nil {
+		t.Fatalf("JSON5() error = %v", err)
+	}
+	want := `{"connect":{"endpoints":["tcp/10.0.0.2:7447"]},"mode":"client","scouting":{"delay":500},` +
+		`"timestamping":{"enabled":{"client":true,"peer":true,"router":true}}}`
+	if got != want {
+		t.Errorf("JSON5() = %s, want %s", got, want)
+	}
+
+	if err := cfg.Insert("mode/nested", "1"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected Validate() to reject an insert below a non-object value")
+	}
+	if err := cfg.Insert("scouting", "{unterminated"); err == nil {
+		t.Error("Expected Insert to reject invalid JSON5")
+	}
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	dir := t.TempDir()
+	pem := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("-----BEGIN CERTIFICATE-----"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ca, cert, key := pem("ca.pem"), pem("cert.pem"), pem("key.pem")
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{
+			name:    "tls without root CA",
+			config:  ClientConfig("tls/robot.local:7447"),
+			wantErr: "root_ca_certificate",
+		},
+		{
+			name:   "tls with root CA",
+			config: ClientConfig("tls/robot.local:7447").WithTLS(TLSConfig{RootCACertificate: ca}),
+		},
+		{
+			name:   "root CA in endpoint config",
+			config: ClientConfig("quic/robot.local:7447#root_ca_certificate=" + ca),
+		},
+		{
+			name:    "missing certificate file",
+			config:  ClientConfig("tls/robot.local:7447").WithTLS(TLSConfig{RootCACertificate: filepath.Join(dir, "none.pem")}),
+			wantErr: "root_ca_certificate",
+		},
+		{
+			name:    "mtls without client certificate",
+			config:  ClientConfig("tls/robot.local:7447").WithTLS(TLSConfig{RootCACertificate: ca, EnableMTLS: true}),
+			wantErr: "connect_certificate, connect_private_key",
+		},
+		{
+			name:    "quic listener without key",
+			config:  PeerConfig("quic/0.0.0.0:7447").WithTLS(TLSConfig{ListenCertificate: cert}),
+			wantErr: "listen_private_key",
+		},
+		{
+			name:   "quic listener",
+			config: PeerConfig("quic/0.0.0.0:7447").WithTLS(TLSConfig{ListenCertificate: cert, ListenPrivateKey: key}),
+		},
+		{
+			name:   "tcp needs nothing",
+			config: ClientConfig("tcp/robot.local:7447"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want mention of %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := ClientConfig("tls/robot.local:7447").WithTLS(TLSConfig{RootCACertificate: "/ca.pem", SkipNameVerification: true})
+	got, _ := cfg.JSON5()
+	if !strings.Contains(got, `"transport":{"link":{"tls":{"root_ca_certificate":"/ca.pem","verify_name_on_connect":false}}}`) {
+		t.Errorf("JSON5() missing TLS section: %s", got)
+	}
+}
+
+func TestConfigAuth(t *testing.T) {
+	cfg := ClientConfig("tcp/router:7447").WithAuth(AuthConfig{User: "robot", Password: "s3cret"})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	doc, _ := cfg.JSON5()
+	if !strings.Contains(doc, `"usrpwd":{"password":"s3cret","user":"robot"}`) {
+		t.Errorf("JSON5() missing credentials: %s", doc)
+	}
+
+	loaded, err := ConfigFromJSON5(`{transport: {auth: {usrpwd: {user: "a", password: "from-file"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Insert("transport/link/tls/connect_private_key_base64", `"a2V5"`); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Config{cfg, loaded} {
+		for _, out := range []string{c.String(), fmt.Sprintf("%v", c), fmt.Sprintf("%+v", c), fmt.Sprintf("%#v", c)} {
+			if strings.Contains(out, "s3cret") || strings.Contains(out, "from-file") || strings.Contains(out, "a2V5") {
+				t.Errorf("secret not redacted: %s", out)
+			}
+		}
+	}
+	if !strings.Contains(cfg.String(), `"user":"robot"`) {
+		t.Errorf("String() = %s, want user", cfg.String())
+	}
+
+	if err := cfg.WithAuth(AuthConfig{User: "robot"}).Validate(); err == nil {
+		t.Error("Validate() accepted user without password")
+	}
+	missing := AuthConfig{DictionaryFile: filepath.Join(t.TempDir(), "users.txt")}
+	if err := cfg.WithAuth(missing).Validate(); err == nil {
+		t.Error("Validate() accepted missing dictionary file")
+	}
+}
+
+func TestParseJSON5(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{`{a: 1, 'b': "x", c: [true, null,],}`, `{"a":1,"b":"x","c":[true,null]}`, false},
+		{"/* c */ {a: 0x1F, b: .5, c: +5., d: 007} // end", `{"a":31,"b":0.5,"c":5,"d":7}`, false},
+		{`'it\'s \u00e9'`, `"it's é"`, false},
+		{`"\x41\x7e \uD83D\uDE00"`, `"A~ 😀"`, false},
+		{"\"a\\\r\nb\\\u2028c\\\nd\"", `"abcd"`, false},
+		{`"\x4"`, "", true},
+		{`"\uD83D"`, "", true},
+		{`"\1"`, "", true},
+		{`{a: Infinity}`, "", true},
+		{`{a: 1 b: 2}`, "", true},
+		{`[1, 2`, "", true},
+		{`-inf`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := parseJSON5(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSON5() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, _ := json.Marshal(v)
+			if string(got) != tt.want {
+				t.Errorf("parseJSON5() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPubSub(t *testing.T) {
 	session, err := Open(DefaultConfig())
 	if err != nil {
@@ -120,6 +399,131 @@ func TestPubSub(t *testing.T) {
 	}
 }
 
+func TestSubscribeChan(t *testing.T) {
+	session, _ := Open(DefaultConfig())
+	defer session.Close()
+	pub, _ := session.Publisher("joints/head")
+
+	if _, err := session.SubscribeChan("joints/*", FifoChannel(0)); !errors.Is(err, ErrSubscribeFailed) {
+		t.Errorf("SubscribeChan(capacity 0) error = %v, want ErrSubscribeFailed", err)
+	}
+
+	// FIFO: the third Put blocks until a sample is received
+	fifo, err := session.SubscribeChan("joints/*", FifoChannel(2))
+	if err != nil {
+		t.Fatalf("SubscribeChan failed: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, p := range []string{"0", "1", "2"} {
+			pub.Put([]byte(p))
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatal("Put did not block on a full FIFO")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for _, want := range []string{"0", "1", "2"} {
+		s, err := fifo.Recv(context.Background())
+		if err != nil || string(s.Payload) != want {
+			t.Fatalf("Recv() = %q, %v; want %q", s.Payload, err, want)
+		}
+	}
+	<-done
+	fifo.Close()
+	if _, err := fifo.Recv(context.Background()); err != ErrSubscriberClosed {
+		t.Errorf("Recv() after Close error = %v, want ErrSubscriberClosed", err)
+	}
+
+	// Ring: Put never blocks and only the latest samples are kept
+	ring, _ := session.SubscribeChan("joints/*", RingChannel(2))
+	for _, p := range []string{"0", "1", "2", "3", "4"} {
+		pub.Put([]byte(p))
+	}
+	for _, want := range []string{"3", "4"} {
+		if s, ok := ring.TryRecv(); !ok || string(s.Payload) != want {
+			t.Fatalf("TryRecv() = %q, %v; want %q", s.Payload, ok, want)
+		}
+	}
+	if _, ok := ring.TryRecv(); ok {
+		t.Error("TryRecv() returned a sample from an empty queue")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ring.Recv(ctx); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Recv() error = %v, want ErrTimeout", err)
+	}
+
+	session.Close()
+	if _, err := ring.Recv(context.Background()); err != ErrSubscriberClosed {
+		t.Errorf("Recv() after session Close error = %v, want ErrSubscriberClosed", err)
+	}
+}
+
+func TestDeclareKeyExpr(t *testing.T) {
+	session, _ := Open(DefaultConfig())
+	defer session.Close()
+
+	if _, err := session.DeclareKeyExpr("robot//joints"); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("DeclareKeyExpr(invalid) error = %v, want ErrInvalidKeyExpr", err)
+	}
+
+	ke, err := session.DeclareKeyExpr("robot/joints")
+	if err != nil {
+		t.Fatalf("DeclareKeyExpr failed: %v", err)
+	}
+	if ke.KeyExpr() != "robot/joints" {
+		t.Errorf("KeyExpr() = %q", ke.KeyExpr())
+	}
+
+	received := make(chan Sample, 1)
+	sub, err := ke.Subscribe(func(s Sample) { received <- s })
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	pub, err := ke.Publisher()
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+	pub.Put([]byte("42"))
+	select {
+	case s := <-received:
+		if s.KeyExpr != "robot/joints" {
+			t.Errorf("received on %q", s.KeyExpr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for sample")
+	}
+	if samples, err := ke.Get(context.Background()); err != nil || len(samples) != 1 {
+		t.Errorf("Get() = %d samples, %v; want 1", len(samples), err)
+	}
+	sub.Close()
+
+	if err := ke.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := ke.Close(); err != nil {
+		t.Errorf("second Close error = %v", err)
+	}
+	if _, err := ke.Publisher(); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Publisher after Close error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := ke.Subscribe(func(Sample) {}); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Subscribe after Close error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := ke.Get(context.Background()); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Get after Close error = %v, want ErrInvalidKeyExpr", err)
+	}
+
+	session.Close()
+	if _, err := session.DeclareKeyExpr("robot/joints"); err != ErrSessionClosed {
+		t.Errorf("DeclareKeyExpr after session Close error = %v, want ErrSessionClosed", err)
+	}
+}
+
 func TestWildcardSubscription(t *testing.T) {
 	session, err := Open(DefaultConfig())
 	if err != nil {
@@ -159,6 +563,28 @@ func TestWildcardSubscription(t *testing.T) {
 	}
 }
 
+func TestSubChunkWildcardSubscription(t *testing.T) {
+	session, _ := Open(DefaultConfig())
+	defer session.Close()
+
+	sub, err := session.SubscribeChan("robot/**/joint_$*", FifoChannel(8))
+	if err != nil {
+		t.Fatalf("SubscribeChan failed: %v", err)
+	}
+	for _, key := range []KeyExpr{"robot/arm/joint_1", "robot/joint_head", "robot/arm/joints", "robot/arm/joint_2/x"} {
+		pub, _ := session.Publisher(key)
+		pub.Put([]byte(key))
+	}
+
+	var got []string
+	for s, ok := sub.TryRecv(); ok; s, ok = sub.TryRecv() {
+		got = append(got, string(s.KeyExpr))
+	}
+	if strings.Join(got, ",") != "robot/arm/joint_1,robot/joint_head" {
+		t.Errorf("received %v, want [robot/arm/joint_1 robot/joint_head]", got)
+	}
+}
+
 func TestDoubleStarWildcard(t *testing.T) {
 	session, err := Open(DefaultConfig())
 	if err != nil {
@@ -226,52 +652,1493 @@ func TestGet(t *testing.T) {
 	}
 }
 
-func TestSessionClose(t *testing.T) {
+func TestGetContextDone(t *testing.T) {
 	session, err := Open(DefaultConfig())
 	if err != nil {
 		t.Fatalf("Open failed: %v", err)
 	}
+	defer session.Close()
 
-	session.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := session.Get(ctx, "data/**"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
 
-	// Operations should fail after close
-	_, err = session.Publisher("test")
-	if err != ErrSessionClosed {
-		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	_, err = session.Get(ctx, "data/**")
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrTimeout wrapping DeadlineExceeded, got %v", err)
 	}
+}
 
-	_, err = session.Subscribe("test", func(s Sample) {})
-	if err != ErrSessionClosed {
-		t.Errorf("Expected ErrSessionClosed, got %v", err)
+func TestReplyError(t *testing.T) {
+	err := errors.Join(&ReplyError{Payload: []byte("not found")})
+
+	if !errors.Is(err, ErrQueryFailed) {
+		t.Errorf("Expected error to match ErrQueryFailed, got %v", err)
+	}
+
+	var replyErr *ReplyError
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("Expected *ReplyError, got %T", err)
+	}
+	if string(replyErr.Payload) != "not found" {
+		t.Errorf("Expected payload %q, got %q", "not found", replyErr.Payload)
 	}
 }
 
-func TestKeyExprMatch(t *testing.T) {
-	tests := []struct {
-		pattern string
-		subject string
-		want    bool
-	}{
-		{"a/b", "a/b", true},
-		{"a/b", "a/c", false},
-		{"a/*", "a/b", true},
-		{"a/*", "a/b/c", false},
-		{"a/**", "a/b", true},
-		{"a/**", "a/b/c", true},
-		{"a/**", "a/b/c/d", true},
-		{"**", "anything", true},
-		{"**", "a/b/c", true},
-		{"a/*/c", "a/b/c", true},
-		{"a/*/c", "a/b/d", false},
+func TestQueryable(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
 	}
+	defer session.Close()
 
-	for _, tt := range tests {
-		t.Run(tt.pattern+"_"+tt.subject, func(t *testing.T) {
-			got := matchKeyExpr(KeyExpr(tt.pattern), KeyExpr(tt.subject))
-			if got != tt.want {
-				t.Errorf("matchKeyExpr(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
-			}
-		})
+	var received Query
+	q, err := session.DeclareQueryable("robot/state/*", func(q Query) {
+		received = q
+		q.Reply("robot/state/head", []byte("idle"))
+		q.ReplyDelete("robot/state/arm")
+		q.ReplyErr([]byte("antennas offline"))
+	})
+	if err != nil {
+		t.Fatalf("DeclareQueryable failed: %v", err)
+	}
+
+	samples, err := session.Get(context.Background(), "robot/state/**",
+		WithParameters("detail=full"), WithPayload([]byte("ping")))
+
+	var replyErr *ReplyError
+	if !errors.As(err, &replyErr) || string(replyErr.Payload) != "antennas offline" {
+		t.Errorf("Expected reply error %q, got %v", "antennas offline", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 replies, got %d", len(samples))
+	}
+	if samples[0].KeyExpr != "robot/state/head" || samples[0].String() != "idle" || samples[0].Kind != SampleKindPut {
+		t.Errorf("Unexpected first reply: %+v", samples[0])
+	}
+	if samples[1].KeyExpr != "robot/state/arm" || samples[1].Kind != SampleKindDelete {
+		t.Errorf("Unexpected second reply: %+v", samples[1])
+	}
+
+	if got := received.Selector(); got != "robot/state/**?detail=full" {
+		t.Errorf("Expected selector %q, got %q", "robot/state/**?detail=full", got)
+	}
+	if string(received.Payload) != "ping" {
+		t.Errorf("Expected query payload %q, got %q", "ping", received.Payload)
+	}
+	if err := received.Reply("robot/state/head", nil); !errors.Is(err, ErrQueryFailed) {
+		t.Errorf("Expected ErrQueryFailed replying after handler returned, got %v", err)
+	}
+
+	q.Close()
+	samples, err = session.Get(context.Background(), "robot/state/**")
+	if err != nil || len(samples) != 0 {
+		t.Errorf("Expected no replies after Close, got %d (err=%v)", len(samples), err)
+	}
+}
+
+func TestGetStream(t *testing.T) {
+	session, _ := Open(DefaultConfig())
+	defer session.Close()
+
+	// The handler waits for the first reply to be streamed before going on
+	streamed := make(chan struct{})
+	session.DeclareQueryable("robot/state/*", func(q Query) {
+		q.Reply("robot/state/head", []byte("idle"))
+		<-streamed
+		q.ReplyErr([]byte("antennas offline"))
+		q.Reply("robot/state/arm", []byte("moving"))
+	})
+
+	var got []string
+	for reply, err := range session.GetStream(context.Background(), "robot/state/**") {
+		var replyErr *ReplyError
+		switch {
+		case errors.As(err, &replyErr):
+			got = append(got, "error:"+string(replyErr.Payload))
+		case err != nil:
+			t.Fatalf("GetStream error = %v", err)
+		default:
+			got = append(got, string(reply.KeyExpr)+"="+reply.String())
+		}
+		if len(got) == 1 {
+			close(streamed)
+		}
+	}
+	want := []string{"robot/state/head=idle", "error:antennas offline", "robot/state/arm=moving"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("GetStream replies = %v, want %v", got, want)
+	}
+
+	// A query that never ends is cut short by ctx
+	session.DeclareQueryable("robot/slow", func(q Query) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var lastErr error
+	for _, err := range session.GetStream(ctx, "robot/slow") {
+		lastErr = err
+	}
+	if !errors.Is(lastErr, ErrTimeout) {
+		t.Errorf("GetStream last error = %v, want ErrTimeout", lastErr)
 	}
 }
 
+func TestSubscriberSamples(t *testing.T) {
+	session, _ := Open(DefaultConfig())
+	defer session.Close()
+
+	sub, _ := session.SubscribeChan("joints/*", FifoChannel(8))
+	pub, _ := session.Publisher("joints/head")
+	for _, p := range []string{"0", "1", "2"} {
+		pub.Put([]byte(p))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []string
+	for s := range sub.Samples(ctx) {
+		got = append(got, s.String())
+		if len(got) == 3 {
+			cancel()
+		}
+	}
+	if strings.Join(got, ",") != "0,1,2" {
+		t.Errorf("Samples() = %v, want [0 1 2]", got)
+	}
+}
+
+func TestLiveliness(t *testing.T) {
+	watcher, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer watcher.Close()
+
+	robot, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	var received []Sample
+	var mu sync.Mutex
+	sub, err := watcher.SubscribeLiveliness("liveliness_test/*/alive", func(s Sample) {
+		mu.Lock()
+		received = append(received, s)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("SubscribeLiveliness failed: %v", err)
+	}
+	defer sub.Close()
+
+	if _, err := robot.DeclareLivelinessToken("liveliness_test/r1/alive"); err != nil {
+		t.Fatalf("DeclareLivelinessToken failed: %v", err)
+	}
+
+	alive, err := watcher.GetLiveliness(context.Background(), "liveliness_test/**")
+	if err != nil {
+		t.Fatalf("GetLiveliness failed: %v", err)
+	}
+	if len(alive) != 1 || alive[0].KeyExpr != "liveliness_test/r1/alive" {
+		t.Errorf("Expected one alive token, got %+v", alive)
+	}
+
+	// History replays tokens that are already alive
+	var history []Sample
+	late, err := watcher.SubscribeLiveliness("liveliness_test/**", func(s Sample) {
+		mu.Lock()
+		history = append(history, s)
+		mu.Unlock()
+	}, WithHistory())
+	if err != nil {
+		t.Fatalf("SubscribeLiveliness failed: %v", err)
+	}
+	late.Close()
+
+	// Closing the session drops its tokens
+	robot.Close()
+
+	alive, err = watcher.GetLiveliness(context.Background(), "liveliness_test/**")
+	if err != nil {
+		t.Fatalf("GetLiveliness failed: %v", err)
+	}
+	if len(alive) != 0 {
+		t.Errorf("Expected no alive tokens after Close, got %d", len(alive))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(history) != 1 || history[0].Kind != SampleKindPut {
+		t.Errorf("Expected one PUT from history, got %+v", history)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Expected PUT and DELETE, got %d samples", len(received))
+	}
+	if received[0].Kind != SampleKindPut || received[1].Kind != SampleKindDelete {
+		t.Errorf("Expected PUT then DELETE, got %s then %s", received[0].Kind, received[1].Kind)
+	}
+}
+
+func TestLivelinessSubscriberCloseWaitsForHandler(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	sub, err := session.SubscribeLiveliness("robot/slow/**", func(Sample) {
+		close(started)
+		<-release
+	})
+	if err != nil {
+		t.Fatalf("SubscribeLiveliness failed: %v", err)
+	}
+
+	go func() {
+		token, err := session.DeclareLivelinessToken("robot/slow/alive")
+		if err == nil {
+			defer token.Close()
+		}
+	}()
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		sub.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while the handler was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-closed
+}
+
+func TestAttachment(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	received := make(chan Sample, 1)
+	sub, err := session.Subscribe("robot/command", func(s Sample) {
+		received <- s
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	pub, _ := session.Publisher("robot/command")
+	meta := map[string]string{"request_id": "42", "trace": "abc"}
+	if err := pub.Put([]byte("look_left"), WithAttachmentMap(meta)); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	select {
+	case s := <-received:
+		got, err := DecodeAttachment(s.Attachment)
+		if err != nil {
+			t.Fatalf("DecodeAttachment failed: %v", err)
+		}
+		if len(got) != 2 || got["request_id"] != "42" || got["trace"] != "abc" {
+			t.Errorf("Expected attachment %v, got %v", meta, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for sample")
+	}
+
+	if err := pub.Delete(WithAttachment([]byte("x"))); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for DELETE attachment, got %v", err)
+	}
+}
+
+func TestDecodeAttachmentInvalid(t *testing.T) {
+	valid := EncodeAttachment(map[string]string{"k": "v"})
+
+	for _, data := range [][]byte{nil, valid[:len(valid)-1], append(valid, 0)} {
+		if _, err := DecodeAttachment(data); err == nil {
+			t.Errorf("DecodeAttachment(%v) expected error", data)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   Encoding
+		output string
+	}{
+		{"", EncodingZenohBytes, "zenoh/bytes"},
+		{"zenoh/bytes", EncodingZenohBytes, "zenoh/bytes"},
+		{"application/json", EncodingApplicationJSON, "application/json"},
+		{"application/json;JointState", EncodingApplicationJSON.WithSchema("JointState"), "application/json;JointState"},
+		{"video/vp9", EncodingVideoVP9, "video/vp9"},
+		{"custom/format", EncodingZenohBytes.WithSchema("custom/format"), "zenoh/bytes;custom/format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := ParseEncoding(tt.input)
+			if got != tt.want {
+				t.Errorf("ParseEncoding(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			if got.String() != tt.output {
+				t.Errorf("String() = %q, want %q", got.String(), tt.output)
+			}
+			if ParseEncoding(got.String()) != got {
+				t.Errorf("ParseEncoding(%q) does not round-trip", got.String())
+			}
+		})
+	}
+}
+
+func TestPublisherEncoding(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	received := make(chan Sample, 2)
+	sub, err := session.Subscribe("robot/state", func(s Sample) {
+		received <- s
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	pub, err := session.Publisher("robot/state", WithEncoding(EncodingApplicationJSON))
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+	pub.Put([]byte(`{}`))
+	pub.Put([]byte("idle"), WithEncoding(EncodingTextPlain))
+
+	seen := map[Encoding]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case s := <-received:
+			seen[s.Encoding] = true
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for samples")
+		}
+	}
+	if !seen[EncodingApplicationJSON] || !seen[EncodingTextPlain] {
+		t.Errorf("Expected default and overridden encodings, got %v", seen)
+	}
+}
+
+func TestPublisherQoS(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	received := make(chan Sample, 1)
+	sub, err := session.Subscribe("robot/motors", func(s Sample) {
+		received <- s
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	pub, err := session.Publisher("robot/motors",
+		WithPriority(PriorityRealTime),
+		WithCongestionControl(CongestionControlBlock),
+		WithExpress(true),
+		WithReliability(ReliabilityBestEffort))
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+	pub.Put([]byte("cmd"))
+
+	want := QoS{Priority: PriorityRealTime, CongestionControl: CongestionControlBlock, Express: true}
+	select {
+	case s := <-received:
+		if s.QoS != want {
+			t.Errorf("Expected QoS %+v, got %+v", want, s.QoS)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for sample")
+	}
+
+	if _, err := session.Publisher("robot/motors", WithPriority(0)); !errors.Is(err, ErrPublishFailed) {
+		t.Errorf("Expected ErrPublishFailed for invalid priority, got %v", err)
+	}
+}
+
+func TestPublisherCloseShared(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	sub, err := session.SubscribeChan("robot/status", FifoChannel(4))
+	if err != nil {
+		t.Fatalf("SubscribeChan failed: %v", err)
+	}
+	defer sub.Close()
+
+	// Same key and options: the publishers share one declaration
+	first, err := session.Publisher("robot/status")
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+	second, err := session.Publisher("robot/status")
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+	if err := first.Put([]byte("late")); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed after Close, got %v", err)
+	}
+	if err := second.Put([]byte("alive")); err != nil {
+		t.Fatalf("Put on the remaining publisher failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s, err := sub.Recv(ctx)
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if string(s.Payload) != "alive" {
+		t.Errorf("Expected 'alive', got %q", s.Payload)
+	}
+	second.Close()
+
+	// A new publisher declares the key again
+	third, err := session.Publisher("robot/status")
+	if err != nil {
+		t.Fatalf("Publisher after Close failed: %v", err)
+	}
+	defer third.Close()
+	if err := third.Put([]byte("again")); err != nil {
+		t.Errorf("Put failed: %v", err)
+	}
+}
+
+func TestSubscriberCloseWaitsForHandler(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	sub, err := session.Subscribe("robot/slow", func(s Sample) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	session.Put("robot/slow", []byte("1"))
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for handler")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		sub.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while the handler was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for Close")
+	}
+
+	session.Put("robot/slow", []byte("2"))
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected 1 handler call, got %d", n)
+	}
+}
+
+func TestPublisherAfterSessionClose(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	pub, err := session.Publisher("robot/status")
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+	defer pub.Close()
+
+	// Only the session is closed; the publisher is still open
+	session.Close()
+	if err := pub.Put([]byte("late")); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Put: expected ErrSessionClosed, got %v", err)
+	}
+	if err := pub.Delete(); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Delete: expected ErrSessionClosed, got %v", err)
+	}
+	if err := pub.Close(); err != nil {
+		t.Errorf("Close after session Close failed: %v", err)
+	}
+}
+
+func TestQueryableCloseWaitsForHandler(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	q, err := session.DeclareQueryable("robot/slow", func(query Query) {
+		close(started)
+		<-release
+		query.Reply("robot/slow", []byte("done"))
+	})
+	if err != nil {
+		t.Fatalf("DeclareQueryable failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replies := make(chan []Sample, 1)
+	go func() {
+		samples, _ := session.Get(ctx, "robot/slow")
+		replies <- samples
+	}()
+	<-started
+
+	closed := make(chan struct{})
+	go func() {
+		q.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while the handler was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-closed
+	if samples := <-replies; len(samples) != 1 {
+		t.Errorf("Expected the reply of the running handler, got %d", len(samples))
+	}
+}
+
+func TestConcurrentClose(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	var subs []Subscriber
+	for range 4 {
+		sub, err := session.Subscribe("robot/**", func(s Sample) {})
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+		subs = append(subs, sub)
+	}
+	pub, err := session.Publisher("robot/load")
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+
+	// Put, Close and session Close race; none may fail other than
+	// with ErrSessionClosed, or drop an entity twice
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if err := pub.Put([]byte("x")); err != nil && !errors.Is(err, ErrSessionClosed) {
+					t.Errorf("Put failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for _, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub.Close()
+		}()
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pub.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		session.Close()
+	}()
+	wg.Wait()
+
+	if err := pub.Put([]byte("x")); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+}
+
+func TestSampleMetadata(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	sub, err := session.SubscribeChan("robot/pose", FifoChannel(4))
+	if err != nil {
+		t.Fatalf("SubscribeChan failed: %v", err)
+	}
+	defer sub.Close()
+
+	pub, err := session.Publisher("robot/pose", WithExpress(true))
+	if err != nil {
+		t.Fatalf("Publisher failed: %v", err)
+	}
+	defer pub.Close()
+
+	before := time.Now()
+	pub.Put([]byte("p1"))
+	pub.Delete()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var samples []Sample
+	for range 2 {
+		s, err := sub.Recv(ctx)
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		samples = append(samples, s)
+	}
+
+	id := session.Info().ID
+	for i, s := range samples {
+		if s.Timestamp.IsZero() || s.Timestamp.ID != id {
+			t.Errorf("Sample %d: expected a timestamp from %s, got %+v", i, id, s.Timestamp)
+		}
+		if ts := s.Timestamp.Time(); ts.Before(before.Truncate(time.Microsecond)) || ts.After(s.ReceivedAt) {
+			t.Errorf("Sample %d: timestamp %v not between %v and %v", i, ts, before, s.ReceivedAt)
+		}
+		if !s.QoS.Express {
+			t.Errorf("Sample %d: expected express", i)
+		}
+		if s.SourceInfo == nil || s.SourceInfo.ID != id || s.SourceInfo.SN != uint32(i) {
+			t.Errorf("Sample %d: unexpected source info %+v", i, s.SourceInfo)
+		}
+	}
+	if samples[1].Kind != SampleKindDelete {
+		t.Errorf("Expected DELETE, got %v", samples[1].Kind)
+	}
+	if samples[0].SourceInfo.EntityID != samples[1].SourceInfo.EntityID {
+		t.Error("Expected samples from the same publisher entity")
+	}
+
+	// Liveliness samples are not stamped
+	token, err := session.DeclareLivelinessToken("robot/alive")
+	if err != nil {
+		t.Fatalf("DeclareLivelinessToken failed: %v", err)
+	}
+	defer token.Close()
+	alive, err := session.GetLiveliness(ctx, "robot/alive")
+	if err != nil || len(alive) != 1 {
+		t.Fatalf("GetLiveliness failed: %v, %v", alive, err)
+	}
+	if !alive[0].Timestamp.IsZero() || alive[0].SourceInfo != nil || alive[0].ReceivedAt.IsZero() {
+		t.Errorf("Unexpected liveliness metadata: %+v", alive[0])
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	ts, err := ParseTimestamp("7386690599959157260/33")
+	if err != nil {
+		t.Fatalf("ParseTimestamp failed: %v", err)
+	}
+	if ts.NTP64 != 7386690599959157260 || ts.ID != (ZenohID{0x33}) {
+		t.Errorf("Unexpected timestamp %+v", ts)
+	}
+	if s := ts.String(); s != "7386690599959157260/33" {
+		t.Errorf("Expected round trip, got %q", s)
+	}
+
+	// RFC 3339 form
+	rfc, err := ParseTimestamp(ts.Time().UTC().Format(time.RFC3339Nano) + "/33")
+	if err != nil {
+		t.Fatalf("ParseTimestamp failed: %v", err)
+	}
+	if rfc.Time() != ts.Time() {
+		t.Errorf("Expected %v, got %v", ts.Time(), rfc.Time())
+	}
+
+	for _, bad := range []string{"", "123", "abc/33", "123/", "123/xyz", "123/" + strings.Repeat("f", 33)} {
+		if _, err := ParseTimestamp(bad); err == nil {
+			t.Errorf("ParseTimestamp(%q): expected error", bad)
+		}
+	}
+
+	now := time.Date(2024, 7, 1, 13, 51, 12, 129693000, time.UTC)
+	if got := NewTimestamp(now, ZenohID{1}).Time(); !got.Equal(now) {
+		t.Errorf("NTP64 round trip: expected %v, got %v", now, got)
+	}
+
+	a := Timestamp{NTP64: 10, ID: ZenohID{2}}
+	b := Timestamp{NTP64: 10, ID: ZenohID{1, 1}}
+	c := Timestamp{NTP64: 11, ID: ZenohID{1}}
+	if !a.Before(b) || !b.Before(c) || c.Compare(a) != 1 || a.Compare(a) != 0 {
+		t.Errorf("Unexpected ordering of %v, %v, %v", a, b, c)
+	}
+}
+
+func TestHLC(t *testing.T) {
+	clock := NewHLC(ZenohID{7})
+	last := clock.Now()
+	for range 1000 {
+		ts := clock.Now()
+		if !last.Before(ts) {
+			t.Fatalf("Timestamps not increasing: %v then %v", last, ts)
+		}
+		last = ts
+	}
+
+	// Received timestamps push the clock forward, within bounds
+	ahead := NewTimestamp(time.Now().Add(100*time.Millisecond), ZenohID{8})
+	if err := clock.Update(ahead); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if ts := clock.Now(); !ahead.Before(ts) {
+		t.Errorf("Expected %v after %v", ts, ahead)
+	}
+	if err := clock.Update(NewTimestamp(time.Now().Add(time.Hour), ZenohID{8})); err == nil {
+		t.Error("Expected error for a timestamp an hour ahead")
+	}
+
+	// Samples of a session are ordered by timestamp
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+	sub, err := session.SubscribeChan("robot/tick", FifoChannel(100))
+	if err != nil {
+		t.Fatalf("SubscribeChan failed: %v", err)
+	}
+	for range 100 {
+		session.Put("robot/tick", nil)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var prev Timestamp
+	for range 100 {
+		s, err := sub.Recv(ctx)
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if !prev.Before(s.Timestamp) {
+			t.Fatalf("Sample timestamps not increasing: %v then %v", prev, s.Timestamp)
+		}
+		prev = s.Timestamp
+	}
+}
+
+func TestSubscribeBorrowed(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	type result struct {
+		sample  Sample
+		payload []byte
+		slices  int
+	}
+	received := make(chan result, 1)
+	var kept *Payload
+	sub, err := session.SubscribeBorrowed("robot/scan", func(s Sample, p *Payload) {
+		data, err := io.ReadAll(p)
+		if err != nil || len(data) != p.Len() {
+			t.Errorf("ReadAll: %d bytes of %d, %v", len(data), p.Len(), err)
+		}
+		n := 0
+		for range p.Slices() {
+			n++
+		}
+		kept = p
+		received <- result{s, data, n}
+	})
+	if err != nil {
+		t.Fatalf("SubscribeBorrowed failed: %v", err)
+	}
+	defer sub.Close()
+
+	buf := []byte("ranges")
+	session.Put("robot/scan", buf, WithAttachment([]byte("meta")))
+	copy(buf, "XXXXXX") // the publisher may reuse its buffer
+
+	select {
+	case r := <-received:
+		if string(r.payload) != "ranges" || r.slices != 1 {
+			t.Errorf("Expected 'ranges' in 1 slice, got %q in %d", r.payload, r.slices)
+		}
+		if r.sample.Payload != nil || string(r.sample.Attachment) != "meta" || r.sample.KeyExpr != "robot/scan" {
+			t.Errorf("Unexpected sample %+v", r.sample)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for sample")
+	}
+
+	sub.Close()
+	if kept.Len() != 0 {
+		t.Error("Expected the payload to be released after the handler")
+	}
+}
+
+func TestPayloadFragments(t *testing.T) {
+	p := borrowPayload()
+	defer p.release()
+	p.add([]byte("hello, "))
+	p.add(nil)
+	p.add([]byte("fragmented "))
+	p.add([]byte("world"))
+
+	if p.Len() != 23 || string(p.Bytes()) != "hello, fragmented world" {
+		t.Errorf("Unexpected payload %q (%d bytes)", p.Bytes(), p.Len())
+	}
+	if got := p.AppendTo([]byte(">")); string(got) != ">hello, fragmented world" {
+		t.Errorf("AppendTo: got %q", got)
+	}
+
+	// Small reads cross fragment boundaries
+	var out []byte
+	chunk := make([]byte, 4)
+	for {
+		n, err := p.Read(chunk)
+		out = append(out, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+	}
+	if string(out) != "hello, fragmented world" {
+		t.Errorf("Read: got %q", out)
+	}
+
+	p.off = 3
+	var sb strings.Builder
+	if n, err := p.WriteTo(&sb); err != nil || n != 20 || sb.String() != "lo, fragmented world" {
+		t.Errorf("WriteTo: %d, %v, %q", n, err, sb.String())
+	}
+}
+
+func TestSubscribePooled(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer session.Close()
+
+	received := make(chan string, 2)
+	sub, err := session.SubscribePooled("robot/odom", func(s Sample) {
+		received <- string(s.Payload)
+	})
+	if err != nil {
+		t.Fatalf("SubscribePooled failed: %v", err)
+	}
+	defer sub.Close()
+
+	session.Put("robot/odom", []byte("first"))
+	session.Put("robot/odom", []byte("second"))
+	got := map[string]bool{}
+	for range 2 {
+		select {
+		case s := <-received:
+			got[s] = true
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for sample")
+		}
+	}
+	if !got["first"] || !got["second"] {
+		t.Errorf("Expected both samples, got %v", got)
+	}
+}
+
+func TestSessionPutDelete(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	sub, err := session.SubscribeChan("robot/state", FifoChannel(2))
+	if err != nil {
+		t.Fatalf("SubscribeChan failed: %v", err)
+	}
+	defer sub.Close()
+
+	err = session.Put("robot/state", []byte("ok"),
+		WithEncoding(EncodingTextPlain),
+		WithPriority(PriorityInteractiveHigh),
+		WithAttachment([]byte("meta")))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := session.Delete("robot/state"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	put, err := sub.Recv(ctx)
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if put.Kind != SampleKindPut || string(put.Payload) != "ok" ||
+		put.Encoding != EncodingTextPlain || string(put.Attachment) != "meta" ||
+		put.QoS.Priority != PriorityInteractiveHigh {
+		t.Errorf("Unexpected put sample: %+v", put)
+	}
+	del, err := sub.Recv(ctx)
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if del.Kind != SampleKindDelete || del.KeyExpr != "robot/state" {
+		t.Errorf("Unexpected delete sample: %+v", del)
+	}
+
+	if err := session.Put("robot//state", nil); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Expected ErrInvalidKeyExpr, got %v", err)
+	}
+	if err := session.Delete("robot/state", WithAttachment([]byte("x"))); err == nil {
+		t.Error("Expected error for delete with attachment")
+	}
+
+	session.Close()
+	if err := session.Put("robot/state", nil); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+}
+
+func TestSessionClose(t *testing.T) {
+	session, err := Open(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	session.Close()
+
+	// Operations should fail after close
+	_, err = session.Publisher("test")
+	if err != ErrSessionClosed {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+
+	_, err = session.Subscribe("test", func(s Sample) {})
+	if err != ErrSessionClosed {
+		t.Errorf("Expected ErrSessionClosed, got %v", err)
+	}
+}
+
+func TestKeyExprValidate(t *testing.T) {
+	tests := []struct {
+		keyExpr   KeyExpr
+		canonical KeyExpr // "" if Canonize must fail
+		valid     bool
+	}{
+		{"robot/joints/head", "robot/joints/head", true},
+		{"robot/*/head", "robot/*/head", true},
+		{"robot/**", "robot/**", true},
+		{"robot/joint_$*", "robot/joint_$*", true},
+		{"robot/$*a$*", "robot/$*a$*", true},
+		{"@admin/robot", "@admin/robot", true},
+		{"", "", false},
+		{"/robot", "", false},
+		{"robot/", "", false},
+		{"robot//head", "", false},
+		{"robot/he#ad", "", false},
+		{"robot/head?x=1", "", false},
+		{"robot/head*", "", false},
+		{"robot/a**", "", false},
+		{"robot/$", "", false},
+		{"robot/$a", "", false},
+		{"robot/@a$*", "", false},
+		{"robot/**/**", "robot/**", false},
+		{"robot/**/*", "robot/*/**", false},
+		{"robot/**/*/**/*", "robot/*/*/**", false},
+		{"robot/$*", "robot/*", false},
+		{"robot/a$*$*b", "robot/a$*b", false},
+	}
+
+	for _, tt := range tests {
+		err := tt.keyExpr.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) error = %v, want valid=%v", tt.keyExpr, err, tt.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidKeyExpr) {
+			t.Errorf("Validate(%q) error = %v, want ErrInvalidKeyExpr", tt.keyExpr, err)
+		}
+
+		got, err := tt.keyExpr.Canonize()
+		if got != tt.canonical || (err == nil) != (tt.canonical != "") {
+			t.Errorf("Canonize(%q) = %q, %v; want %q", tt.keyExpr, got, err, tt.canonical)
+		}
+	}
+
+	session, _ := Open(DefaultConfig())
+	defer session.Close()
+	if _, err := session.Publisher("robot//head"); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Publisher error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := session.Subscribe("robot/**/**", func(Sample) {}); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Subscribe error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := session.Get(context.Background(), "robot/"); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Get error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := session.DeclareQueryable("robot/#", func(Query) {}); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("DeclareQueryable error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := session.DeclareLivelinessToken("robot//alive"); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("DeclareLivelinessToken error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := session.SubscribeLiveliness("robot/**/**", func(Sample) {}); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("SubscribeLiveliness error = %v, want ErrInvalidKeyExpr", err)
+	}
+	if _, err := session.GetLiveliness(context.Background(), "robot/?"); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("GetLiveliness error = %v, want ErrInvalidKeyExpr", err)
+	}
+}
+
+func TestKeyExprMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/*", "a/b", true},
+		{"a/*", "a/b/c", false},
+		{"a/**", "a/b", true},
+		{"a/**", "a/b/c", true},
+		{"a/**", "a/b/c/d", true},
+		{"**", "anything", true},
+		{"**", "a/b/c", true},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/d", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.subject, func(t *testing.T) {
+			got := Intersects(KeyExpr(tt.pattern), KeyExpr(tt.subject))
+			if got != tt.want {
+				t.Errorf("Intersects(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestKeyExprAlgebra checks Intersects and Includes between wildcard
+// expressions against the results of z_keyexpr_intersects and
+// z_keyexpr_includes.
+func TestKeyExprAlgebra(t *testing.T) {
+	tests := []struct {
+		a, b       KeyExpr
+		intersects bool
+		includes   bool // a includes b
+	}{
+		{"a", "a", true, true},
+		{"a", "b", false, false},
+		{"a/b", "a", false, false},
+		{"*", "a", true, true},
+		{"a", "*", true, false},
+		{"*", "*", true, true},
+		{"*", "a/b", false, false},
+		{"**", "a/b/c", true, true},
+		{"a/b/c", "**", true, false},
+		{"**", "*", true, true},
+		{"*", "**", true, false},
+		{"**", "**", true, true},
+		{"a/**", "a", true, true},
+		{"a", "a/**", true, false},
+		{"a/**", "a/*/c", true, true},
+		{"a/*/c", "a/**", true, false},
+		{"a/**/c", "a/b/**", true, false},
+		{"a/b/**", "a/**/c", true, false},
+		{"a/**/c", "a/*/d", false, false},
+		{"a/**/c/**", "a/*/c", true, true},
+		{"*/b/**", "a/*/c", true, false},
+		{"*/**", "a/b/c", true, true},
+		{"*/**", "**", true, false},
+		{"a/*/**", "a", false, false},
+		{"**/z", "a/b/z", true, true},
+		{"**/z", "a/b/y", false, false},
+		{"**/b/**", "a/**/c", true, false},
+		{"@a", "@a", true, true},
+		{"*", "@a", false, false},
+		{"**", "@a/b", false, false},
+		{"a/**", "a/@b", false, false},
+		{"a/**/c", "a/@b/c", false, false},
+		{"a/@b/**", "a/@b/c", true, true},
+
+		// Sub-chunk wildcards
+		{"joint_$*", "joint_7", true, true},
+		{"joint_$*", "joint_", true, true},
+		{"joint_$*", "joints", false, false},
+		{"$*_raw", "cam_raw", true, true},
+		{"$*_raw", "cam_raw/x", false, false},
+		{"a$*b$*c", "abc", true, true},
+		{"a$*b$*c", "axxbyyc", true, true},
+		{"a$*b$*c", "axxbyy", false, false},
+		{"a$*", "$*b", true, false},
+		{"*", "a$*", true, true},
+		{"a$*", "*", true, false},
+		{"$*a$*", "$*a", true, true},
+		{"$*a", "$*a$*", true, false},
+		{"a$*c", "a$*b$*c", true, true},
+		{"a$*b$*c", "a$*c", true, false},
+		{"a$*", "b$*", false, false},
+		{"$*", "@a", false, false},
+		{"robot/**/joint_$*", "robot/arm/left/joint_3", true, true},
+		{"robot/**/joint_$*", "robot/**/joint_1", true, true},
+		{"robot/**/joint_1", "robot/**/joint_$*", true, false},
+		{"robot/**/joint_$*", "robot/joint_$*/x", false, false},
+		{"**/cam_$*", "robot/*/cam_$*", true, true},
+	}
+
+	for _, tt := range tests {
+		if got := Intersects(tt.a, tt.b); got != tt.intersects {
+			t.Errorf("Intersects(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.intersects)
+		}
+		if got := Intersects(tt.b, tt.a); got != tt.intersects {
+			t.Errorf("Intersects(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.intersects)
+		}
+		if got := Includes(tt.a, tt.b); got != tt.includes {
+			t.Errorf("Includes(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.includes)
+		}
+	}
+}
+
+func TestKeyFormat(t *testing.T) {
+	f, err := NewKeyFormat("reachy_mini/${robot_id:*}/joint_${name:$*#head}/${rest:**}")
+	if err != nil {
+		t.Fatalf("NewKeyFormat failed: %v", err)
+	}
+	if got := f.KeyExpr(); got != "reachy_mini/*/joint_$*/**" {
+		t.Errorf("KeyExpr() = %q", got)
+	}
+
+	key, err := f.Format(map[string]string{"robot_id": "r1", "rest": "pos/deg"})
+	if err != nil || key != "reachy_mini/r1/joint_head/pos/deg" {
+		t.Errorf("Format() = %q, %v", key, err)
+	}
+	if !Includes(f.KeyExpr(), key) {
+		t.Errorf("KeyExpr() does not include %q", key)
+	}
+	for _, values := range []map[string]string{
+		{"rest": "pos"},                                  // robot_id missing
+		{"robot_id": "r1/r2", "rest": "pos"},             // * spans one chunk
+		{"robot_id": "r1", "name": "a/b", "rest": "pos"}, // $* stays in its chunk
+	} {
+		if _, err := f.Format(values); !errors.Is(err, ErrInvalidKeyExpr) {
+			t.Errorf("Format(%v) error = %v, want ErrInvalidKeyExpr", values, err)
+		}
+	}
+
+	captures, err := f.Parse("reachy_mini/r2/joint_neck/vel/rad/s")
+	want := map[string]string{"robot_id": "r2", "name": "neck", "rest": "vel/rad/s"}
+	if err != nil || fmt.Sprint(captures) != fmt.Sprint(want) {
+		t.Errorf("Parse() = %v, %v; want %v", captures, err, want)
+	}
+	for _, key := range []KeyExpr{"reachy_mini/r2/neck/vel", "other/r2/joint_neck/vel", "reachy_mini/r2/joint_neck/"} {
+		if _, err := f.Parse(key); !errors.Is(err, ErrInvalidKeyExpr) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidKeyExpr", key, err)
+		}
+	}
+
+	// ** captures may match zero chunks, as the key expression does
+	key, err = f.Format(map[string]string{"robot_id": "r1", "rest": ""})
+	if err != nil || key != "reachy_mini/r1/joint_head" || !Includes(f.KeyExpr(), key) {
+		t.Errorf("Format(empty rest) = %q, %v", key, err)
+	}
+	captures, err = f.Parse("reachy_mini/r2/joint_neck")
+	want = map[string]string{"robot_id": "r2", "name": "neck", "rest": ""}
+	if err != nil || fmt.Sprint(captures) != fmt.Sprint(want) {
+		t.Errorf("Parse(no rest) = %v, %v; want %v", captures, err, want)
+	}
+	g, err := NewKeyFormat("${prefix:**}/status")
+	if err != nil || g.KeyExpr() != "**/status" {
+		t.Fatalf("NewKeyFormat() = %q, %v", g.KeyExpr(), err)
+	}
+	for key, prefix := range map[KeyExpr]string{"status": "", "a/b/status": "a/b"} {
+		if captures, err := g.Parse(key); err != nil || captures["prefix"] != prefix {
+			t.Errorf("Parse(%q) = %v, %v; want prefix %q", key, captures, err, prefix)
+		}
+	}
+
+	// $* starting a chunk does not match a verbatim chunk, as in KeyExpr
+	h, err := NewKeyFormat("fleet/${id:$*}_cam/$*_${n:$*}")
+	if err != nil {
+		t.Fatalf("NewKeyFormat failed: %v", err)
+	}
+	for key, ok := range map[KeyExpr]bool{
+		"fleet/r1_cam/x_y": true, "fleet/_cam/_y": true,
+		"fleet/@r1_cam/x_y": false, "fleet/r1_cam/@x_y": false,
+	} {
+		if _, err := h.Parse(key); (err == nil) != ok || ok != Intersects(h.KeyExpr(), key) {
+			t.Errorf("Parse(%q) error = %v, KeyExpr() %q matches: %v", key, err, h.KeyExpr(), ok)
+		}
+	}
+	if _, err := h.Format(map[string]string{"id": "@r1", "n": "y"}); !errors.Is(err, ErrInvalidKeyExpr) {
+		t.Errorf("Format(verbatim id) error = %v, want ErrInvalidKeyExpr", err)
+	}
+
+	for _, format := range []string{
+		"robot/${id}",
+		"robot/${id:*",
+		"robot/${id:*}/${id:*}",
+		"robot/${1d:*}",
+		"robot/${id:}",
+		"robot/${id:*#a/b}",
+		"robot//${id:*}",
+	} {
+		if _, err := NewKeyFormat(format); !errors.Is(err, ErrInvalidKeyExpr) {
+			t.Errorf("NewKeyFormat(%q) error = %v, want ErrInvalidKeyExpr", format, err)
+		}
+	}
+}
+
+func TestKeyExprTree(t *testing.T) {
+	patterns := []KeyExpr{
+		"robot/joints/head", "robot/*/head", "robot/**", "robot/**/head",
+		"**/b/**", "robot/joint_$*/pos", "robot/$*_$*", "@admin/**", "robot/@cfg",
+		"a/b/c", "*/b", "**",
+	}
+	keys := []KeyExpr{
+		"robot/joints/head", "robot/arm/head", "robot", "robot/joint_3/pos",
+		"robot/a_b", "robot/ab", "a/b/b/c", "a/b", "@admin/x", "robot/@cfg",
+		"x/@cfg/y", "robot/*", "robot/joint_$*/**",
+	}
+
+	var tree KeyExprTree[KeyExpr]
+	for _, p := range patterns {
+		if err := tree.Insert(p, p); err != nil {
+			t.Fatalf("Insert(%q) failed: %v", p, err)
+		}
+	}
+	for _, p := range []KeyExpr{"robot//head", "@a$*"} {
+		if err := tree.Insert(p, "x"); !errors.Is(err, ErrInvalidKeyExpr) {
+			t.Errorf("Insert(%q) error = %v, want ErrInvalidKeyExpr", p, err)
+		}
+	}
+
+	// The tree must agree with a linear scan, yielding each value once
+	for _, key := range keys {
+		var want, got []string
+		for _, p := range patterns {
+			if Intersects(p, key) {
+				want = append(want, string(p))
+			}
+		}
+		tree.Match(key, func(p KeyExpr) bool {
+			got = append(got, string(p))
+			return true
+		})
+		slices.Sort(got)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("Match(%q) = %v, want %v", key, got, want)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.Match("robot/joint_3/pos", func(KeyExpr) bool { return true })
+	})
+	if allocs != 0 {
+		t.Errorf("Match allocated %v times, want 0", allocs)
+	}
+
+	for _, p := range patterns {
+		if !tree.Remove(p, p) {
+			t.Errorf("Remove(%q) = false", p)
+		}
+	}
+	if tree.Remove("robot/**", "robot/**") || tree.Len() != 0 {
+		t.Errorf("tree not empty after Remove: Len() = %d", tree.Len())
+	}
+	tree.Match("robot/joints/head", func(p KeyExpr) bool {
+		t.Errorf("Match after Remove yielded %q", p)
+		return true
+	})
+}
+
+func TestKeyExprJoinConcat(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func() (KeyExpr, error)
+		want    KeyExpr
+		wantErr bool
+	}{
+		{"join", func() (KeyExpr, error) { return Join("robot", "joints/head") }, "robot/joints/head", false},
+		{"join canonizes", func() (KeyExpr, error) { return Join("robot/**", "*") }, "robot/*/**", false},
+		{"join invalid", func() (KeyExpr, error) { return Join("robot/", "head") }, "", true},
+		{"join empty", func() (KeyExpr, error) { return Join("robot", "") }, "", true},
+		{"concat", func() (KeyExpr, error) { return Concat("robot/joint_", "7") }, "robot/joint_7", false},
+		{"concat chunk", func() (KeyExpr, error) { return Concat("robot", "/*") }, "robot/*", false},
+		{"concat stars", func() (KeyExpr, error) { return Concat("robot/*", "*") }, "", true},
+		{"concat invalid result", func() (KeyExpr, error) { return Concat("robot", "/") }, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.fn()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s = %q, %v; want %q (error %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidKeyExpr) {
+			t.Errorf("%s error = %v, want ErrInvalidKeyExpr", tt.name, err)
+		}
+	}
+}
+
+// benchmarkPatterns returns subscriber-like key expressions for n robots.
+func benchmarkPatterns(n int) []KeyExpr {
+	patterns := make([]KeyExpr, 0, 4*n)
+	for i := range n {
+		robot := fmt.Sprintf("fleet/robot_%d", i)
+		patterns = append(patterns,
+			KeyExpr(robot+"/joints/*"),
+			KeyExpr(robot+"/joint_$*/pos"),
+			KeyExpr(robot+"/**"),
+			KeyExpr(robot+"/camera/raw"),
+		)
+	}
+	return patterns
+}
+
+func BenchmarkKeyExprTreeMatch(b *testing.B) {
+	var tree KeyExprTree[int]
+	for i, p := range benchmarkPatterns(250) {
+		tree.Insert(p, i)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		tree.Match("fleet/robot_125/joints/head", func(int) bool { return true })
+	}
+}
+
+// BenchmarkKeyExprLinearMatch is the baseline for the tree: the mock
+// dispatch it replaced, which called Intersects for every subscriber.
+// Most patterns differ from the key in a literal chunk, which Intersects
+// rejects without building a sub-chunk table.
+func BenchmarkKeyExprLinearMatch(b *testing.B) {
+	const key = "fleet/robot_125/joints/head"
+	patterns := benchmarkPatterns(250)
+
+	var tree KeyExprTree[KeyExpr]
+	for _, p := range patterns {
+		tree.Insert(p, p)
+	}
+	linear := func() (n int) {
+		for _, p := range patterns {
+			if Intersects(p, key) {
+				n++
+			}
+		}
+		return n
+	}
+	var want int
+	tree.Match(key, func(KeyExpr) bool { want++; return true })
+	if got := linear(); got != want {
+		b.Fatalf("linear scan matched %d patterns, tree %d", got, want)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		linear()
+	}
+}
+
+// benchmarkSample is a 1 KiB sample with its header already converted,
+// so the delivery benchmarks measure only how each mode hands over the
+// payload, not the backend's routing or the cgo header conversion.
+var benchmarkSample = Sample{KeyExpr: "robot/joints", Payload: make([]byte, 1024)}
+
+func BenchmarkSubscribeCopy(b *testing.B) {
+	var sum byte
+	handler := func(sample Sample) { sum += sample.Payload[0] }
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkSample.Payload)))
+	for b.Loop() {
+		sample := benchmarkSample
+		sample.Payload = bytes.Clone(sample.Payload)
+		handler(sample)
+	}
+}
+
+func benchmarkBorrowed(b *testing.B, handler BorrowedHandler) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchmarkSample.Payload)))
+	for b.Loop() {
+		payload := borrowPayload()
+		payload.add(benchmarkSample.Payload)
+		sample := benchmarkSample
+		sample.Payload = nil
+		handler(sample, payload)
+		payload.release()
+	}
+}
+
+func BenchmarkSubscribeBorrowed(b *testing.B) {
+	var sum byte
+	benchmarkBorrowed(b, func(sample Sample, p *Payload) {
+		for frag := range p.Slices() {
+			sum += frag[0]
+		}
+	})
+}
+
+func BenchmarkSubscribePooled(b *testing.B) {
+	var sum byte
+	benchmarkBorrowed(b, pooledHandler(func(sample Sample) {
+		sum += sample.Payload[0]
+	}))
+}
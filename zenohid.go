@@ -0,0 +1,66 @@
+package zenoh
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ZenohID identifies a Zenoh session, peer or router.
+// The zero value means the ID is unknown.
+type ZenohID [16]byte
+
+// String returns the ID in hexadecimal, as shown by Zenoh tools:
+// the bytes are read as a little-endian 128-bit number, without
+// leading zeros.
+func (id ZenohID) String() string {
+	var be [16]byte
+	for i, b := range id {
+		be[len(id)-1-i] = b
+	}
+	s := strings.TrimLeft(hex.EncodeToString(be[:]), "0")
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// IsZero reports whether the ID is unknown.
+func (id ZenohID) IsZero() bool {
+	return id == ZenohID{}
+}
+
+// ParseZenohID parses an ID in the form returned by String.
+func ParseZenohID(s string) (ZenohID, error) {
+	if s == "" || len(s) > 32 {
+		return ZenohID{}, fmt.Errorf("zenoh: invalid ID %q", s)
+	}
+	digits := s
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+	be, err := hex.DecodeString(digits)
+	if err != nil {
+		return ZenohID{}, fmt.Errorf("zenoh: invalid ID %q", s)
+	}
+
+	var id ZenohID
+	for i, b := range be {
+		id[len(be)-1-i] = b
+	}
+	return id, nil
+}
+
+// Compare returns -1, 0 or +1 as the ID is less than, equal to or
+// greater than other, read as 128-bit numbers like in String.
+func (id ZenohID) Compare(other ZenohID) int {
+	for i := len(id) - 1; i >= 0; i-- {
+		switch {
+		case id[i] < other[i]:
+			return -1
+		case id[i] > other[i]:
+			return 1
+		}
+	}
+	return 0
+}